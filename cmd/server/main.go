@@ -13,6 +13,7 @@ import (
 	"syncdocs/internal/auth"
 	"syncdocs/internal/config"
 	"syncdocs/internal/database"
+	"syncdocs/internal/events"
 	"syncdocs/internal/github"
 	"syncdocs/internal/syncer"
 	"syncdocs/internal/tasks" // Import tasks
@@ -26,7 +27,9 @@ func main() {
 	}
 
 	// Initialize database connection
-	dbPool, err := database.ConnectDB(cfg.DatabaseURL)
+	// Each running sync and the scheduler leader hold a connection for their advisory lock,
+	// and the sync event relay one to listen on
+	dbPool, err := database.ConnectDB(cfg.DatabaseURL, cfg.SyncWorkers+2)
 	if err != nil {
 		log.Fatalf("Failed to connect to database: %v", err)
 	}
@@ -46,11 +49,23 @@ func main() {
 	// Create RepositoryStore instance
 	repoStore := database.NewRepositoryStore(dbPool)
 
+	// Sync progress events, streamed to clients over Server-Sent Events
+	eventBus := events.NewBus()
+	// Relay the events through PostgreSQL so clients of any instance see every sync
+	eventRelay := events.NewRelay(eventBus, repoStore)
+	eventRelay.Start()
+	defer eventRelay.Stop()
+
 	// Initialize Syncer
-	appSyncer := syncer.NewSyncer(repoStore, githubClient)
+	appSyncer := syncer.NewSyncer(cfg, repoStore, githubClient, eventBus)
+
+	// Initialize and start the sync job queue workers
+	syncQueue := tasks.NewQueue(cfg, repoStore, appSyncer)
+	syncQueue.Start()
+	defer syncQueue.Stop()
 
 	// Initialize and start Task Scheduler
-	scheduler := tasks.NewScheduler(cfg, appSyncer)
+	scheduler := tasks.NewScheduler(cfg, repoStore, syncQueue)
 	scheduler.Start()
 	// Ensure scheduler is stopped on shutdown (though defer might not run on fatal errors)
 	// A more robust solution involves signal handling for graceful shutdown.
@@ -71,7 +86,14 @@ func main() {
 	apiGroup := router.Group("/api", authMiddleware)
 	{
 		// Register API routes, passing the authenticated group and dependencies
-		api.RegisterRoutes(apiGroup, repoStore, appSyncer, githubClient) // Pass repoStore, appSyncer, and githubClient
+		api.RegisterRoutes(apiGroup, repoStore, appSyncer, syncQueue, scheduler, githubClient) // Pass repoStore, appSyncer, syncQueue, scheduler and githubClient
+	}
+
+	// GitHub webhook routes sit outside Basic Auth; deliveries are verified by HMAC signature
+	if cfg.GithubWebhookSecret != "" {
+		api.RegisterWebhookRoutes(router.Group("/webhooks"), repoStore, syncQueue, cfg.GithubWebhookSecret)
+	} else {
+		log.Println("GITHUB_WEBHOOK_SECRET not set; GitHub webhook endpoint disabled.")
 	}
 
 	// Serve frontend static files
@@ -82,7 +104,7 @@ func main() {
 	// Handle SPA routing: For any route not matched by API or static files, serve index.html
 	router.NoRoute(func(c *gin.Context) {
 		// Check if the request path starts with /api/ - if so, it's a 404 for the API
-		if strings.HasPrefix(c.Request.URL.Path, "/api/") {
+		if strings.HasPrefix(c.Request.URL.Path, "/api/") || strings.HasPrefix(c.Request.URL.Path, "/webhooks/") {
 			c.JSON(http.StatusNotFound, gin.H{"error": "API endpoint not found"})
 			return
 		}
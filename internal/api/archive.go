@@ -0,0 +1,175 @@
+package api
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"syncdocs/internal/database"
+)
+
+// archiveTypes maps the ?type= values of the archive endpoint to their filename extension
+// and Content-Type.
+var archiveTypes = map[string]struct{ extension, contentType string }{
+	"zip":    {"zip", "application/zip"},
+	"tar.gz": {"tar.gz", "application/gzip"},
+}
+
+// DownloadRepositoryArchiveHandler handles GET /api/repositories/:id/archive requests.
+// It streams the files of the last sync as a zip (default) or, with ?type=tar.gz, a gzipped
+// tarball. With a single source path, files keep their paths relative to it; with several,
+// their paths relative to the repository root, so files of different source paths cannot
+// collide. The archive is built from the stored files; GitHub is not queried.
+func (a *API) DownloadRepositoryArchiveHandler(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid repository ID format"})
+		return
+	}
+	archiveType := c.DefaultQuery("type", "zip")
+	kind, ok := archiveTypes[archiveType]
+	if !ok {
+		c.JSON(http.StatusBadRequest, ErrorResponse{
+			Error: fmt.Sprintf("Unknown archive type '%s'; available types: tar.gz, zip", archiveType),
+		})
+		return
+	}
+
+	repo, err := a.Store.GetRepositoryByID(c.Request.Context(), id)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			c.JSON(http.StatusNotFound, ErrorResponse{Error: err.Error()})
+		} else {
+			log.Printf("Error getting repository %d for archive: %v", id, err)
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to retrieve repository files"})
+		}
+		return
+	}
+
+	files, err := a.Store.ListRepositoryFiles(c.Request.Context(), id)
+	if err != nil {
+		log.Printf("Error getting files of repository %d for archive: %v", id, err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to retrieve repository files"})
+		return
+	}
+	if len(files) == 0 {
+		c.JSON(http.StatusNotFound, ErrorResponse{
+			Error: "No synced files available for this repository yet. Please sync first.",
+		})
+		return
+	}
+
+	entries, err := archiveEntries(files, repo.EffectiveSourcePaths())
+	if err != nil {
+		log.Printf("Error building archive of repository %d: %v", id, err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: err.Error()})
+		return
+	}
+	modTime := time.Now()
+	if repo.LastSyncTime.Valid {
+		modTime = repo.LastSyncTime.Time
+	}
+
+	filename := fmt.Sprintf("%s_%s_%s_docs.%s",
+		repo.RepoName, filenamePart(repo.RefLabel()), filenamePart(repo.DocsPath), kind.extension)
+	c.Header("Content-Disposition", "attachment; filename="+filename)
+	c.Header("Content-Type", kind.contentType)
+	c.Status(http.StatusOK)
+
+	if archiveType == "tar.gz" {
+		err = writeTarGz(c.Writer, entries, modTime)
+	} else {
+		err = writeZip(c.Writer, entries, modTime)
+	}
+	if err != nil {
+		// Log the error, as headers and status have already been sent
+		log.Printf("Error streaming %s archive of repository %d: %v", archiveType, id, err)
+	}
+}
+
+// archiveEntry is a file to write to an archive.
+type archiveEntry struct {
+	Path    string
+	Content string
+}
+
+// archiveEntries returns the archive entries for the synced files. Paths are made relative
+// to the source path if there is only one (a file given as the source path keeps its base
+// name), and kept relative to the repository root otherwise. Two files mapping to the same
+// entry are reported as an error rather than written twice.
+func archiveEntries(files []database.RepositoryFile, sources []database.SourcePath) ([]archiveEntry, error) {
+	prefix := ""
+	if len(sources) == 1 {
+		prefix = strings.Trim(sources[0].Path, "/")
+	}
+
+	entries := make([]archiveEntry, 0, len(files))
+	seen := make(map[string]string, len(files))
+	for _, file := range files {
+		entryPath := strings.TrimPrefix(file.Path, "/")
+		switch {
+		case prefix == "":
+		case entryPath == prefix:
+			entryPath = path.Base(entryPath)
+		case strings.HasPrefix(entryPath, prefix+"/"):
+			entryPath = strings.TrimPrefix(entryPath, prefix+"/")
+		}
+		if other, ok := seen[entryPath]; ok {
+			return nil, fmt.Errorf("files '%s' and '%s' would both be stored as '%s' in the archive",
+				other, file.Path, entryPath)
+		}
+		seen[entryPath] = file.Path
+		entries = append(entries, archiveEntry{Path: entryPath, Content: file.Content})
+	}
+	return entries, nil
+}
+
+// writeZip writes the entries as a zip archive.
+func writeZip(w io.Writer, entries []archiveEntry, modTime time.Time) error {
+	zw := zip.NewWriter(w)
+	for _, entry := range entries {
+		fw, err := zw.CreateHeader(&zip.FileHeader{Name: entry.Path, Method: zip.Deflate, Modified: modTime})
+		if err != nil {
+			return fmt.Errorf("failed to add %s to archive: %w", entry.Path, err)
+		}
+		if _, err := io.WriteString(fw, entry.Content); err != nil {
+			return fmt.Errorf("failed to write %s to archive: %w", entry.Path, err)
+		}
+	}
+	return zw.Close()
+}
+
+// writeTarGz writes the entries as a gzip-compressed tar archive.
+func writeTarGz(w io.Writer, entries []archiveEntry, modTime time.Time) error {
+	gw := gzip.NewWriter(w)
+	tw := tar.NewWriter(gw)
+	for _, entry := range entries {
+		header := &tar.Header{
+			Typeflag: tar.TypeReg,
+			Name:     entry.Path,
+			Mode:     0o644,
+			Size:     int64(len(entry.Content)),
+			ModTime:  modTime,
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			return fmt.Errorf("failed to add %s to archive: %w", entry.Path, err)
+		}
+		if _, err := io.WriteString(tw, entry.Content); err != nil {
+			return fmt.Errorf("failed to write %s to archive: %w", entry.Path, err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return fmt.Errorf("failed to finish archive: %w", err)
+	}
+	return gw.Close()
+}
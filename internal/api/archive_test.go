@@ -0,0 +1,106 @@
+package api
+
+import (
+	"slices"
+	"testing"
+
+	"syncdocs/internal/database"
+)
+
+func TestArchiveEntries(t *testing.T) {
+	files := func(paths ...string) []database.RepositoryFile {
+		result := make([]database.RepositoryFile, len(paths))
+		for i, p := range paths {
+			result[i] = database.RepositoryFile{Path: p, Content: "content of " + p}
+		}
+		return result
+	}
+	sources := func(paths ...string) []database.SourcePath {
+		result := make([]database.SourcePath, len(paths))
+		for i, p := range paths {
+			result[i] = database.SourcePath{Path: p}
+		}
+		return result
+	}
+
+	tests := []struct {
+		name    string
+		files   []database.RepositoryFile
+		sources []database.SourcePath
+		want    []string
+		wantErr bool
+	}{
+		{
+			name:    "single source strips its prefix",
+			files:   files("docs/index.md", "docs/guide/setup.md"),
+			sources: sources("docs"),
+			want:    []string{"index.md", "guide/setup.md"},
+		},
+		{
+			name:    "single source with slashes",
+			files:   files("docs/index.md"),
+			sources: sources("/docs/"),
+			want:    []string{"index.md"},
+		},
+		{
+			name:    "single file source keeps its base name",
+			files:   files("docs/README.md"),
+			sources: sources("docs/README.md"),
+			want:    []string{"README.md"},
+		},
+		{
+			name:    "prefix only matches whole segments",
+			files:   files("docs/a.md", "docs-extra/b.md"),
+			sources: sources("docs"),
+			want:    []string{"a.md", "docs-extra/b.md"},
+		},
+		{
+			name:    "repository root keeps full paths",
+			files:   files("README.md", "docs/index.md"),
+			sources: sources(""),
+			want:    []string{"README.md", "docs/index.md"},
+		},
+		{
+			name:    "several sources keep full paths",
+			files:   files("docs/index.md", "api/index.md", "README.md"),
+			sources: sources("docs", "api", "README.md"),
+			want:    []string{"docs/index.md", "api/index.md", "README.md"},
+		},
+		{
+			name:    "colliding entries",
+			files:   files("docs/README.md", "README.md"),
+			sources: sources("docs"),
+			wantErr: true,
+		},
+		{
+			name:    "duplicate paths",
+			files:   files("docs/a.md", "/docs/a.md"),
+			sources: sources("docs", "api"),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, err := archiveEntries(tt.files, tt.sources)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("archiveEntries() = %+v, want an error", entries)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("archiveEntries() error: %v", err)
+			}
+			var got []string
+			for i, entry := range entries {
+				got = append(got, entry.Path)
+				if entry.Content != tt.files[i].Content {
+					t.Errorf("entry %s has content %q, want %q", entry.Path, entry.Content, tt.files[i].Content)
+				}
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("archiveEntries() paths = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package api
+
+import (
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// keepaliveInterval is how often an idle event stream sends a comment line, so proxies
+// and clients do not time the connection out.
+const keepaliveInterval = 15 * time.Second
+
+// RepositoryEventsHandler handles GET /api/repositories/:id/events requests.
+// It streams the sync progress events of one repository as Server-Sent Events.
+func (a *API) RepositoryEventsHandler(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid repository ID format"})
+		return
+	}
+
+	if _, err := a.Store.GetRepositoryByID(c.Request.Context(), id); err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			c.JSON(http.StatusNotFound, ErrorResponse{Error: err.Error()})
+		} else {
+			log.Printf("Error finding repository %d before streaming events: %v", id, err)
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to find repository"})
+		}
+		return
+	}
+
+	a.streamEvents(c, id)
+}
+
+// EventsHandler handles GET /api/events requests.
+// It streams the sync progress events of all repositories as Server-Sent Events.
+func (a *API) EventsHandler(c *gin.Context) {
+	a.streamEvents(c, 0)
+}
+
+// streamEvents writes the events of a repository (or all repositories if repoID is 0)
+// to the client until it disconnects. Syncs running in other instances are reported
+// through the event relay, with file progress throttled; events sent while its listener
+// reconnects are missed.
+func (a *API) streamEvents(c *gin.Context, repoID int) {
+	bus := a.Syncer.Events
+	sub := bus.Subscribe(repoID)
+	defer bus.Unsubscribe(sub)
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no") // Disable response buffering in nginx
+
+	keepalive := time.NewTicker(keepaliveInterval)
+	defer keepalive.Stop()
+
+	// Send the headers right away so the client knows the stream is open
+	c.Status(http.StatusOK)
+	c.Writer.Flush()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-c.Request.Context().Done():
+			return false
+		case event, ok := <-sub.C:
+			if !ok {
+				return false
+			}
+			c.SSEvent(event.Type, event)
+			return true
+		case <-keepalive.C:
+			_, err := io.WriteString(w, ": keepalive\n\n")
+			return err == nil
+		}
+	})
+}
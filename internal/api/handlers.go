@@ -1,12 +1,14 @@
 package api
 
 import (
-	"context" // Import context
+	"context"
 	// "errors" // Removed unused import
 	"fmt"
 	"io" // Import for io.Copy
 	"log"
 	"net/http"
+	"reflect"
+	"slices"
 	"strconv"
 	"strings"
 
@@ -14,22 +16,30 @@ import (
 	// "github.com/jackc/pgx/v5" // Removed unused import
 
 	"syncdocs/internal/database"
+	"syncdocs/internal/diff"
+	"syncdocs/internal/format"
 	gh "syncdocs/internal/github" // Import github client
+	"syncdocs/internal/pathfilter"
 	"syncdocs/internal/syncer"   // Import syncer
+	"syncdocs/internal/tasks"
 )
 
 // API holds dependencies for API handlers.
 type API struct {
 	Store        *database.RepositoryStore
 	Syncer       *syncer.Syncer // Add Syncer dependency
-	GithubClient *gh.Client     // Add GithubClient dependency
+	Queue        *tasks.Queue     // Sync jobs are enqueued rather than run inline
+	Scheduler    *tasks.Scheduler // Kept in step with repository schedules
+	GithubClient *gh.Client       // Add GithubClient dependency
 }
 
 // NewAPI creates a new API instance with dependencies.
-func NewAPI(store *database.RepositoryStore, syncer *syncer.Syncer, githubClient *gh.Client) *API {
+func NewAPI(store *database.RepositoryStore, syncer *syncer.Syncer, queue *tasks.Queue, scheduler *tasks.Scheduler, githubClient *gh.Client) *API {
 	return &API{
 		Store:        store,
-		Syncer:       syncer,       // Assign Syncer
+		Syncer:       syncer, // Assign Syncer
+		Queue:        queue,
+		Scheduler:    scheduler,
 		GithubClient: githubClient, // Assign GithubClient
 	}
 }
@@ -49,79 +59,135 @@ func (a *API) CreateRepositoryHandler(c *gin.Context) {
 		return
 	}
 
-	// Basic validation for extensions (comma-separated, no empty parts)
-	extParts := strings.Split(payload.Extensions, ",")
-	validExtensions := []string{}
-	for _, ext := range extParts {
-		trimmedExt := strings.TrimSpace(ext)
-		if trimmedExt != "" {
-			validExtensions = append(validExtensions, trimmedExt)
+	if status, err := a.prepareCreatePayload(c.Request.Context(), &payload); err != nil {
+		c.JSON(status, ErrorResponse{Error: err.Error()})
+		return
+	}
+
+	repo, err := a.Store.CreateRepository(c.Request.Context(), payload, payload.Branch)
+	if err != nil {
+		// Check for specific "already exists" error
+		if strings.Contains(err.Error(), "already exists") {
+			c.JSON(http.StatusConflict, ErrorResponse{Error: err.Error()})
+		} else {
+			log.Printf("Error creating repository: %v", err)
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to create repository"})
 		}
+		return
 	}
-	if len(validExtensions) == 0 {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Extensions cannot be empty or contain only commas/spaces"})
+	a.startRepository(c.Request.Context(), repo)
+
+	// Return the created repository details
+	c.JSON(http.StatusCreated, newRepositoryListItem(repo))
+}
+
+// BulkCreateRepositoriesHandler handles POST /api/repositories/bulk requests.
+// It creates one repository entry per ref in the payload, sharing the rest of the
+// configuration. Either every entry is created or none is.
+func (a *API) BulkCreateRepositoriesHandler(c *gin.Context) {
+	var payload database.RepositoryBulkCreatePayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid request payload: " + err.Error()})
+		return
+	}
+	if payload.RefMode == database.RefModeLatestRelease {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "refs cannot be combined with ref_mode 'latest_release'"})
 		return
 	}
-	payload.Extensions = strings.Join(validExtensions, ",") // Use cleaned extensions
 
-	// Determine the branch to store
-	branchToStore := payload.Branch
-	if branchToStore == "" {
-		owner, repoName, err := gh.ParseRepoURL(payload.URL)
-		if err != nil {
-			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid repository URL: " + err.Error()})
+	payloads := make([]database.RepositoryCreatePayload, 0, len(payload.Refs))
+	seen := make(map[string]bool, len(payload.Refs))
+	for _, ref := range payload.Refs {
+		ref = strings.TrimSpace(ref)
+		if ref == "" {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "refs must not contain empty entries"})
 			return
 		}
-		defaultBranch, err := a.GithubClient.GetDefaultBranch(c.Request.Context(), owner, repoName)
-		if err != nil {
-			log.Printf("Error getting default branch for %s/%s: %v", owner, repoName, err)
-			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to determine default branch for repository: " + err.Error()})
+		if seen[ref] {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: fmt.Sprintf("Duplicate ref '%s'", ref)})
+			return
+		}
+		seen[ref] = true
+
+		entry := payload.RepositoryCreatePayload
+		entry.Branch, entry.Ref = "", ref
+		if entry.RefMode == database.RefModeSemver {
+			entry.Ref, entry.RefPattern = "", ref // Each entry follows its own tag pattern
+		}
+		if status, err := a.prepareCreatePayload(c.Request.Context(), &entry); err != nil {
+			c.JSON(status, ErrorResponse{Error: fmt.Sprintf("Ref '%s': %v", ref, err)})
 			return
 		}
-		branchToStore = defaultBranch
-		log.Printf("No branch provided for %s, using default branch: %s", payload.URL, branchToStore)
+		payloads = append(payloads, entry)
 	}
 
-	repo, err := a.Store.CreateRepository(c.Request.Context(), payload, branchToStore)
+	repos, err := a.Store.CreateRepositories(c.Request.Context(), payloads)
 	if err != nil {
-		// Check for specific "already exists" error
 		if strings.Contains(err.Error(), "already exists") {
 			c.JSON(http.StatusConflict, ErrorResponse{Error: err.Error()})
 		} else {
-			log.Printf("Error creating repository: %v", err)
-			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to create repository"})
+			log.Printf("Error creating %d repositories: %v", len(payloads), err)
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to create repositories"})
 		}
 		return
 	}
 
-	// Trigger initial sync in the background
-	go func(repoID int) {
-		log.Printf("Triggering initial sync for newly created repository ID: %d (branch: %s)", repoID, repo.Branch)
-		// Use context.Background for the background task
-		err := a.Syncer.SyncRepositoryByID(context.Background(), repoID)
-		if err != nil {
-			log.Printf("Error during initial sync for repo ID %d (branch: %s): %v", repoID, repo.Branch, err)
-			// Note: Status might already be 'failed' if SyncRepositoryByID updated it.
-			// We could potentially update status here again, but it might conflict.
-		} else {
-			log.Printf("Initial sync completed for repo ID %d (branch: %s).", repoID, repo.Branch)
-		}
-	}(repo.ID) // Pass the new repo ID to the goroutine
+	items := make([]database.RepositoryListItem, 0, len(repos))
+	for _, repo := range repos {
+		a.startRepository(c.Request.Context(), repo)
+		items = append(items, newRepositoryListItem(repo))
+	}
+	c.JSON(http.StatusCreated, items)
+}
 
-	// Return the created repository details (consider using ListItem for consistency)
-	listItem := database.RepositoryListItem{
-		ID:             repo.ID,
-		URL:            repo.URL,
-		DocsPath:       repo.DocsPath,
-		Extensions:     repo.Extensions,
-		Branch:         repo.Branch, // Add branch to response
-		LastSyncStatus: repo.LastSyncStatus,
-		LastSyncTime:   repo.LastSyncTime,
-		LastSyncError:  repo.LastSyncError.String, // Convert NullString
-		UpdatedAt:      repo.UpdatedAt,
+// prepareCreatePayload validates and normalizes a create payload: the patterns, source paths
+// and schedule, and the ref, which is checked against GitHub and left in Branch as it is to
+// be stored. On failure it also returns the HTTP status to answer with.
+func (a *API) prepareCreatePayload(ctx context.Context, payload *database.RepositoryCreatePayload) (int, error) {
+	extensions, patterns, err := resolvePatterns(payload.Extensions, payload.Patterns)
+	if err != nil {
+		return http.StatusBadRequest, err
+	}
+	payload.Extensions, payload.Patterns = extensions, patterns
+
+	sources, err := resolveSourcePaths(payload.DocsPath, payload.SourcePaths)
+	if err != nil {
+		return http.StatusBadRequest, err
+	}
+	payload.DocsPath, payload.SourcePaths = sources[0].Path, sources
+
+	schedule, err := tasks.NormalizeSchedule(payload.Schedule)
+	if err != nil {
+		return http.StatusBadRequest, err
+	}
+	payload.Schedule = schedule
+
+	// Determine the branch, tag or commit to store for the ref mode
+	owner, repoName, err := gh.ParseRepoURL(payload.URL)
+	if err != nil {
+		return http.StatusBadRequest, fmt.Errorf("Invalid repository URL: %w", err)
+	}
+	ref := payload.Branch
+	if payload.Ref != "" {
+		ref = payload.Ref
 	}
+	refSettings := database.Repository{Owner: owner, RepoName: repoName, RefMode: payload.RefMode, Branch: ref, RefPattern: payload.RefPattern}
+	if status, err := a.resolveRefSettings(ctx, &refSettings); err != nil {
+		return status, err
+	}
+	payload.RefMode, payload.RefPattern, payload.Branch, payload.Ref = refSettings.RefMode, refSettings.RefPattern, refSettings.Branch, ""
+	return 0, nil
+}
 
-	c.JSON(http.StatusCreated, listItem)
+// startRepository schedules a newly created repository and queues its initial sync.
+func (a *API) startRepository(ctx context.Context, repo *database.Repository) {
+	a.Scheduler.ScheduleRepository(repo)
+
+	// Queue the initial sync; a worker picks it up in the background
+	if _, err := a.Queue.Enqueue(ctx, repo.ID, syncer.TriggerInitial); err != nil {
+		// The repository is created either way; the next scheduled sync will cover it
+		log.Printf("Error enqueuing initial sync for repo ID %d (branch: %s): %v", repo.ID, repo.Branch, err)
+	}
 }
 
 // ListRepositoriesHandler handles GET /api/repositories requests.
@@ -166,6 +232,9 @@ func (a *API) GetRepositoryHandler(c *gin.Context) {
 }
 
 // UpdateRepositoryHandler handles PUT /api/repositories/:id requests.
+// Omitted fields keep their current value. Patterns are only regenerated from extensions
+// that changed, and docs_path without source_paths only replaces the first source path.
+// Changing the file selection forces a full resync, which is queued right away.
 func (a *API) UpdateRepositoryHandler(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
@@ -179,47 +248,224 @@ func (a *API) UpdateRepositoryHandler(c *gin.Context) {
 		return
 	}
 
-	// Basic validation for extensions
-	extParts := strings.Split(payload.Extensions, ",")
-	validExtensions := []string{}
-	for _, ext := range extParts {
-		trimmedExt := strings.TrimSpace(ext)
-		if trimmedExt != "" {
-			validExtensions = append(validExtensions, trimmedExt)
+	current, err := a.Store.GetRepositoryByID(c.Request.Context(), id)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			c.JSON(http.StatusNotFound, ErrorResponse{Error: err.Error()})
+		} else {
+			log.Printf("Error getting repository %d for update: %v", id, err)
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to retrieve repository"})
+		}
+		return
+	}
+
+	// Clients that only know about extensions and docs_path (such as the web UI) send them
+	// back unchanged, which must not discard custom patterns or additional source paths
+	if len(payload.Patterns) > 0 || payload.Extensions != "" {
+		extensions := payload.Extensions
+		if extensions == "" {
+			extensions = current.Extensions
 		}
+		extensions, patterns, err := resolvePatterns(extensions, payload.Patterns)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+			return
+		}
+		if len(payload.Patterns) == 0 && extensions == current.Extensions && len(current.Patterns) > 0 {
+			patterns = current.Patterns
+		}
+		payload.Extensions, payload.Patterns = extensions, patterns
+	} else {
+		payload.Extensions, payload.Patterns = current.Extensions, current.Patterns
+	}
+
+	sources := current.EffectiveSourcePaths()
+	switch {
+	case len(payload.SourcePaths) > 0:
+		sources = payload.SourcePaths
+	case payload.DocsPath != "":
+		// docs_path alone moves the first source path, keeping its patterns and the others
+		sources = slices.Clone(sources)
+		sources[0].Path = payload.DocsPath
 	}
-	if len(validExtensions) == 0 {
-		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Extensions cannot be empty or contain only commas/spaces"})
+	sources, err = resolveSourcePaths("", sources)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
 		return
 	}
-	payload.Extensions = strings.Join(validExtensions, ",") // Use cleaned extensions
+	payload.DocsPath, payload.SourcePaths = sources[0].Path, sources
+
+	if payload.Schedule != nil {
+		schedule, err := tasks.NormalizeSchedule(*payload.Schedule)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+			return
+		}
+		payload.Schedule = &schedule
+	}
 
-	repo, err := a.Store.UpdateRepository(c.Request.Context(), id, payload)
+	resync := payload.Extensions != current.Extensions || !slices.Equal(payload.Patterns, current.Patterns) ||
+		!reflect.DeepEqual(payload.SourcePaths, current.EffectiveSourcePaths())
+
+	repo, err := a.Store.UpdateRepository(c.Request.Context(), id, payload, resync)
 	if err != nil {
 		if strings.Contains(err.Error(), "not found") {
 			c.JSON(http.StatusNotFound, ErrorResponse{Error: err.Error()})
+		} else if strings.Contains(err.Error(), "already exists") {
+			c.JSON(http.StatusConflict, ErrorResponse{Error: err.Error()})
 		} else {
 			log.Printf("Error updating repository %d: %v", id, err)
 			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to update repository"})
 		}
 		return
 	}
+	a.Scheduler.ScheduleRepository(repo)
 
-	// Return updated details (consider ListItem)
-	listItem := database.RepositoryListItem{
-		ID:             repo.ID,
-		URL:            repo.URL,
-		DocsPath:       repo.DocsPath,
-		Extensions:     repo.Extensions,
-		Branch:         repo.Branch, // Add branch to response
-		LastSyncStatus: repo.LastSyncStatus,
-		LastSyncTime:   repo.LastSyncTime,
-		LastSyncError:  repo.LastSyncError.String,
-		UpdatedAt:      repo.UpdatedAt,
+	if resync {
+		if _, err := a.Queue.Enqueue(c.Request.Context(), repo.ID, syncer.TriggerUpdate); err != nil {
+			// The settings are saved either way; the next scheduled sync runs in full
+			log.Printf("Error enqueuing resync for repo ID %d after update: %v", repo.ID, err)
+		}
 	}
+
+	// Return updated details (consider ListItem)
+	listItem := newRepositoryListItem(repo)
 	c.JSON(http.StatusOK, listItem)
 }
 
+// PatchRepositoryHandler handles PATCH /api/repositories/:id requests.
+// Only the fields present in the payload change. Changing the URL, ref or file selection
+// forces a full resync, which is queued right away.
+func (a *API) PatchRepositoryHandler(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid repository ID format"})
+		return
+	}
+
+	var payload database.RepositoryPatchPayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid request payload: " + err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	repo, err := a.Store.GetRepositoryByID(ctx, id)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			c.JSON(http.StatusNotFound, ErrorResponse{Error: err.Error()})
+		} else {
+			log.Printf("Error getting repository %d for update: %v", id, err)
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to retrieve repository"})
+		}
+		return
+	}
+	before := *repo
+	before.SourcePaths = repo.EffectiveSourcePaths()
+
+	if payload.URL != nil {
+		owner, repoName, err := gh.ParseRepoURL(*payload.URL)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid repository URL: " + err.Error()})
+			return
+		}
+		repo.URL, repo.Owner, repo.RepoName = *payload.URL, owner, repoName
+	}
+
+	if payload.Extensions != nil || payload.Patterns != nil {
+		extensions := repo.Extensions
+		if payload.Extensions != nil {
+			extensions = *payload.Extensions
+		}
+		var patterns []string
+		if payload.Patterns != nil {
+			patterns = *payload.Patterns
+		}
+		// New extensions without patterns replace the patterns derived from them
+		extensions, patterns, err = resolvePatterns(extensions, patterns)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+			return
+		}
+		repo.Extensions, repo.Patterns = extensions, patterns
+	}
+
+	if payload.DocsPath != nil || payload.SourcePaths != nil {
+		var docsPath string
+		var sourcePaths []database.SourcePath
+		if payload.SourcePaths != nil {
+			sourcePaths = *payload.SourcePaths
+		} else {
+			docsPath = *payload.DocsPath
+		}
+		sources, err := resolveSourcePaths(docsPath, sourcePaths)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+			return
+		}
+		repo.DocsPath, repo.SourcePaths = sources[0].Path, sources
+	}
+
+	if payload.AllowPartial != nil {
+		repo.AllowPartial = *payload.AllowPartial
+	}
+
+	if payload.Schedule != nil {
+		schedule, err := tasks.NormalizeSchedule(*payload.Schedule)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: err.Error()})
+			return
+		}
+		repo.Schedule = schedule
+	}
+
+	// The ref is checked against the (possibly new) repository whenever either changes.
+	// Switching ref modes without a new ref starts from the default (e.g., default branch).
+	if payload.URL != nil || payload.Branch != nil || payload.Ref != nil || payload.RefMode != nil || payload.RefPattern != nil {
+		if payload.RefMode != nil && *payload.RefMode != repo.RefMode {
+			repo.RefMode, repo.Branch = *payload.RefMode, ""
+		}
+		if payload.Ref != nil {
+			repo.Branch = *payload.Ref
+		} else if payload.Branch != nil {
+			repo.Branch = *payload.Branch
+		}
+		if payload.RefPattern != nil {
+			repo.RefPattern = *payload.RefPattern
+		}
+		if status, err := a.resolveRefSettings(ctx, repo); err != nil {
+			c.JSON(status, ErrorResponse{Error: err.Error()})
+			return
+		}
+	}
+
+	resync := repo.Owner != before.Owner || repo.RepoName != before.RepoName || repo.Branch != before.Branch ||
+		repo.RefMode != before.RefMode || repo.RefPattern != before.RefPattern || !reflect.DeepEqual(repo.EffectiveSourcePaths(), before.SourcePaths) || !slices.Equal(repo.Patterns, before.Patterns)
+
+	repo, err = a.Store.SaveRepositorySettings(ctx, repo, resync)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			c.JSON(http.StatusNotFound, ErrorResponse{Error: err.Error()})
+		} else if strings.Contains(err.Error(), "already exists") {
+			c.JSON(http.StatusConflict, ErrorResponse{Error: err.Error()})
+		} else {
+			log.Printf("Error updating repository %d: %v", id, err)
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to update repository"})
+		}
+		return
+	}
+	a.Scheduler.ScheduleRepository(repo)
+
+	if resync {
+		if _, err := a.Queue.Enqueue(ctx, repo.ID, syncer.TriggerUpdate); err != nil {
+			// The settings are saved either way; the next scheduled sync runs in full
+			log.Printf("Error enqueuing resync for repo ID %d after update: %v", repo.ID, err)
+		}
+	}
+
+	c.JSON(http.StatusOK, newRepositoryListItem(repo))
+}
+
 // DeleteRepositoryHandler handles DELETE /api/repositories/:id requests.
 func (a *API) DeleteRepositoryHandler(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
@@ -228,6 +474,10 @@ func (a *API) DeleteRepositoryHandler(c *gin.Context) {
 		return
 	}
 
+	// Stop any sync of the repository running in this instance; syncs running elsewhere
+	// stop with their next heartbeat once the repository is gone
+	a.Syncer.CancelSync(id)
+
 	err = a.Store.DeleteRepository(c.Request.Context(), id)
 	if err != nil {
 		if strings.Contains(err.Error(), "not found") {
@@ -238,17 +488,37 @@ func (a *API) DeleteRepositoryHandler(c *gin.Context) {
 		}
 		return
 	}
+	a.Scheduler.UnscheduleRepository(id)
 
 	c.JSON(http.StatusOK, gin.H{"message": fmt.Sprintf("Repository %d deleted successfully", id)})
 }
 
 // DownloadRepositoryContentHandler handles GET /api/repositories/:id/download requests.
+// ?format= selects a registered format (markdown by default; also xml, json, jsonl and
+// plain), which also determines the filename extension and Content-Type. Markdown is the
+// stored aggregated content; the other formats are rendered from the stored files of the
+// last sync (409 if the content predates them). With ?provenance=true, the content is
+// preceded by the repository, ref and commit it was synced from, the sync time and the
+// number of files.
 func (a *API) DownloadRepositoryContentHandler(c *gin.Context) {
 	id, err := strconv.Atoi(c.Param("id"))
 	if err != nil {
 		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid repository ID format"})
 		return
 	}
+	formatName := c.DefaultQuery("format", format.Default)
+	formatter, ok := format.Get(formatName)
+	if !ok {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: fmt.Sprintf("Unknown format '%s'; available formats: %s", formatName, strings.Join(format.Names(), ", "))})
+		return
+	}
+	provenance := false
+	if v := c.Query("provenance"); v != "" {
+		if provenance, err = strconv.ParseBool(v); err != nil {
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "provenance must be a boolean"})
+			return
+		}
+	}
 
 	repo, err := a.Store.GetRepositoryByID(c.Request.Context(), id)
 	if err != nil {
@@ -267,23 +537,66 @@ func (a *API) DownloadRepositoryContentHandler(c *gin.Context) {
 	}
 
 	// Set headers for file download
-	// Use repo name and docs path for a more descriptive filename
-	filename := fmt.Sprintf("%s_%s_docs.md", repo.RepoName, strings.ReplaceAll(repo.DocsPath, "/", "_"))
+	// Use repo name, ref and docs path for a descriptive filename that tells versions apart
+	filename := fmt.Sprintf("%s_%s_%s_docs.%s", repo.RepoName, filenamePart(repo.RefLabel()), filenamePart(repo.DocsPath), formatter.Extension())
+
+	if formatName == format.Default {
+		// The stored aggregate is served as is; only the provenance (if any) is rendered
+		doc := &format.Document{}
+		if provenance {
+			fileCount, err := a.Store.CountRepositoryFiles(c.Request.Context(), id)
+			if err != nil {
+				log.Printf("Error counting files of repository %d for download: %v", id, err)
+				c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to retrieve repository content"})
+				return
+			}
+			doc.Provenance = newProvenance(repo, fileCount)
+		}
+		c.Header("Content-Disposition", "attachment; filename="+filename)
+		c.Header("Content-Type", formatter.ContentType())
+		c.Status(http.StatusOK)
+		err = formatter.Write(c.Writer, doc)
+		if err == nil {
+			_, err = io.WriteString(c.Writer, repo.AggregatedContent.String)
+		}
+		if err != nil {
+			// Log the error, as headers and status might have already been sent
+			log.Printf("Error streaming repository content for ID %d: %v", id, err)
+		}
+		return
+	}
+
+	// Other formats are rendered from the files of the last sync, in aggregation order,
+	// which are stored in the same transaction as the aggregate
+	files, err := a.Store.ListRepositoryFiles(c.Request.Context(), id)
+	if err != nil {
+		log.Printf("Error getting files of repository %d for download: %v", id, err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to retrieve repository content"})
+		return
+	}
+	if len(files) == 0 {
+		// Content synced before the per-file cache was kept with it
+		c.JSON(http.StatusConflict, ErrorResponse{Error: fmt.Sprintf("The files of this repository are not available as %s yet. Please sync again.", formatName)})
+		return
+	}
+	doc := &format.Document{Files: make([]format.File, 0, len(files))}
+	for _, file := range files {
+		doc.Files = append(doc.Files, format.File{Path: file.Path, SHA: file.SHA, Content: file.Content})
+	}
+	if provenance {
+		doc.Provenance = newProvenance(repo, len(files))
+	}
+
 	c.Header("Content-Disposition", "attachment; filename="+filename)
-	c.Header("Content-Type", "text/markdown; charset=utf-8") // Correct MIME type for Markdown
+	c.Header("Content-Type", formatter.ContentType())
 
 	// Set status code
 	c.Status(http.StatusOK)
 
 	// Stream the content
-	reader := strings.NewReader(repo.AggregatedContent.String)
-	_, err = io.Copy(c.Writer, reader)
-	if err != nil {
+	if err := formatter.Write(c.Writer, doc); err != nil {
 		// Log the error, as headers and status might have already been sent
-		log.Printf("Error streaming repository content for ID %d: %v", id, err)
-		// Attempt to write an error to the client if possible, though it might not work
-		// if headers/status already sent. Gin might handle this by aborting.
-		// c.Error(err) // This might be too late or inappropriate here.
+		log.Printf("Error streaming repository content for ID %d as %s: %v", id, formatName, err)
 	}
 }
 
@@ -307,20 +620,529 @@ func (a *API) TriggerSyncHandler(c *gin.Context) {
 		return
 	}
 
-	// Trigger sync in a separate goroutine so the API call returns immediately.
-	// Use a new context that isn't tied to the HTTP request's lifetime for the background task.
-	go func() {
-		// Create a background context for the sync task
-		syncCtx := context.Background()
-		err := a.Syncer.SyncRepositoryByID(syncCtx, id)
+	// Queue the sync so the API call returns immediately and the request survives restarts
+	job, err := a.Queue.Enqueue(c.Request.Context(), id, syncer.TriggerManual)
+	if err != nil {
+		log.Printf("Error enqueuing sync for repository %d: %v", id, err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to enqueue sync"})
+		return
+	}
+
+	// Return Accepted immediately
+	c.JSON(http.StatusAccepted, gin.H{"message": fmt.Sprintf("Sync queued for repository %d. Status will be updated.", id), "job": job})
+}
+
+// CancelSyncHandler handles POST /api/repositories/:id/sync/cancel requests.
+// A sync running in this instance is cancelled right away; one running in another instance
+// is asked to stop and does so with its next heartbeat. Previously stored content is kept.
+func (a *API) CancelSyncHandler(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid repository ID format"})
+		return
+	}
+
+	if a.Syncer.CancelSync(id) {
+		c.JSON(http.StatusOK, gin.H{"message": fmt.Sprintf("Sync of repository %d cancelled.", id)})
+		return
+	}
+
+	syncing, err := a.Store.RequestSyncCancel(c.Request.Context(), id)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			c.JSON(http.StatusNotFound, ErrorResponse{Error: err.Error()})
+		} else {
+			log.Printf("Error requesting sync cancellation for repository %d: %v", id, err)
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to cancel sync"})
+		}
+		return
+	}
+	if !syncing {
+		c.JSON(http.StatusConflict, ErrorResponse{Error: fmt.Sprintf("No sync in progress for repository %d", id)})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"message": fmt.Sprintf("Cancellation of the sync of repository %d requested; the instance running it will stop shortly.", id)})
+}
+
+// resolvePatterns validates the file selection of a create or update payload and returns the
+// cleaned extension list and the patterns to store. Without patterns, the extensions are
+// converted into equivalent "**/*.<ext>" include patterns.
+func resolvePatterns(extensions string, patterns []string) (string, []string, error) {
+	// Clean extensions (comma-separated, no empty parts)
+	validExtensions := []string{}
+	for _, ext := range strings.Split(extensions, ",") {
+		trimmedExt := strings.ToLower(strings.TrimSpace(ext))
+		if trimmedExt != "" {
+			validExtensions = append(validExtensions, trimmedExt)
+		}
+	}
+	extensions = strings.Join(validExtensions, ",")
+
+	if len(patterns) == 0 {
+		if len(validExtensions) == 0 {
+			return "", nil, fmt.Errorf("Either patterns or extensions must be provided")
+		}
+		patterns = pathfilter.ExtensionPatterns(extensions)
+	}
+	patterns, err := pathfilter.Normalize(patterns)
+	if err != nil {
+		return "", nil, fmt.Errorf("Invalid patterns: %w", err)
+	}
+	return extensions, patterns, nil
+}
+
+// resolveRefSettings validates the ref mode of repo against GitHub and normalizes the ref
+// stored in Branch: the branch (the default branch if empty), the tag, the full commit SHA,
+// or nothing for the modes that select a tag at each sync. On failure it also returns the
+// HTTP status to answer with.
+func (a *API) resolveRefSettings(ctx context.Context, repo *database.Repository) (int, error) {
+	repo.Branch = strings.TrimSpace(repo.Branch)
+	repo.RefPattern = strings.TrimSpace(repo.RefPattern)
+	if repo.RefMode != database.RefModeSemver {
+		repo.RefPattern = ""
+	}
+
+	switch repo.RefMode {
+	case "", database.RefModeBranch:
+		repo.RefMode = database.RefModeBranch
+		if repo.Branch == "" {
+			defaultBranch, err := a.GithubClient.GetDefaultBranch(ctx, repo.Owner, repo.RepoName)
+			if err != nil {
+				log.Printf("Error getting default branch for %s/%s: %v", repo.Owner, repo.RepoName, err)
+				return http.StatusInternalServerError, fmt.Errorf("Failed to determine default branch for repository: %w", err)
+			}
+			log.Printf("No branch provided for %s/%s, using default branch: %s", repo.Owner, repo.RepoName, defaultBranch)
+			repo.Branch = defaultBranch
+			return 0, nil
+		}
+		exists, err := a.GithubClient.BranchExists(ctx, repo.Owner, repo.RepoName, repo.Branch)
+		if err != nil {
+			return http.StatusInternalServerError, fmt.Errorf("Failed to verify branch: %w", err)
+		}
+		if !exists {
+			return http.StatusBadRequest, fmt.Errorf("Branch '%s' does not exist in %s/%s", repo.Branch, repo.Owner, repo.RepoName)
+		}
+	case database.RefModeTag:
+		if repo.Branch == "" {
+			return http.StatusBadRequest, fmt.Errorf("A tag name must be provided as ref for ref_mode 'tag'")
+		}
+		exists, err := a.GithubClient.TagExists(ctx, repo.Owner, repo.RepoName, repo.Branch)
+		if err != nil {
+			return http.StatusInternalServerError, fmt.Errorf("Failed to verify tag: %w", err)
+		}
+		if !exists {
+			return http.StatusBadRequest, fmt.Errorf("Tag '%s' does not exist in %s/%s", repo.Branch, repo.Owner, repo.RepoName)
+		}
+	case database.RefModeCommit:
+		if repo.Branch == "" {
+			return http.StatusBadRequest, fmt.Errorf("A commit SHA must be provided as ref for ref_mode 'commit'")
+		}
+		sha, _, err := a.GithubClient.GetCommitSHA(ctx, repo.Owner, repo.RepoName, repo.Branch, "")
+		if err != nil {
+			return http.StatusBadRequest, fmt.Errorf("Commit '%s' cannot be resolved in %s/%s: %w", repo.Branch, repo.Owner, repo.RepoName, err)
+		}
+		repo.Branch = sha // Pin the full SHA, even if given abbreviated
+	case database.RefModeLatestRelease, database.RefModeSemver:
+		if err := syncer.ValidateRefPattern(repo.RefPattern); err != nil {
+			return http.StatusBadRequest, fmt.Errorf("Invalid ref_pattern: %w", err)
+		}
+		repo.Branch = ""
+		// Resolve once now so that a repository without a matching tag is rejected up front
+		if _, err := syncer.ResolveRef(ctx, a.GithubClient, repo); err != nil {
+			return http.StatusBadRequest, fmt.Errorf("Cannot resolve ref for ref_mode '%s': %w", repo.RefMode, err)
+		}
+	default:
+		return http.StatusBadRequest, fmt.Errorf("Invalid ref_mode '%s': must be branch, tag, commit, latest_release or semver", repo.RefMode)
+	}
+	return 0, nil
+}
+
+// newRepositoryListItem builds the list representation of a repository.
+func newRepositoryListItem(repo *database.Repository) database.RepositoryListItem {
+	return database.RepositoryListItem{
+		ID:             repo.ID,
+		Name:           repo.DisplayName(),
+		URL:            repo.URL,
+		DocsPath:       repo.DocsPath,
+		SourcePaths:    repo.EffectiveSourcePaths(),
+		Extensions:     repo.Extensions,
+		Patterns:       repo.Patterns,
+		Branch:         repo.Branch,
+		RefMode:        repo.RefMode,
+		RefPattern:     repo.RefPattern,
+		ResolvedRef:    repo.ResolvedRef,
+		CommitSHA:      repo.LastCommitSHA,
+		AllowPartial:   repo.AllowPartial,
+		Schedule:       repo.Schedule,
+		LastSyncStatus: repo.LastSyncStatus,
+		LastSyncTime:   repo.LastSyncTime,
+		LastSyncError:  repo.LastSyncError.String, // Convert NullString
+		UpdatedAt:      repo.UpdatedAt,
+	}
+}
+
+// resolveSourcePaths validates the source paths of a create or update payload. Without
+// source paths, docsPath becomes the only one. Paths are stored without leading or trailing
+// slashes, with the repository root as an empty path.
+func resolveSourcePaths(docsPath string, sources []database.SourcePath) ([]database.SourcePath, error) {
+	if len(sources) == 0 {
+		if strings.TrimSpace(docsPath) == "" {
+			return nil, fmt.Errorf("Either source_paths or docs_path must be provided")
+		}
+		sources = []database.SourcePath{{Path: docsPath}}
+	}
+
+	resolved := make([]database.SourcePath, 0, len(sources))
+	seen := make(map[string]bool, len(sources))
+	for _, source := range sources {
+		sourcePath := strings.Trim(strings.TrimSpace(source.Path), "/")
+		if sourcePath == "." {
+			sourcePath = ""
+		}
+		if seen[sourcePath] {
+			return nil, fmt.Errorf("Duplicate source path '%s'", source.Path)
+		}
+		seen[sourcePath] = true
+
+		var patterns []string
+		if len(source.Patterns) > 0 {
+			var err error
+			if patterns, err = pathfilter.Normalize(source.Patterns); err != nil {
+				return nil, fmt.Errorf("Invalid patterns for source path '%s': %w", source.Path, err)
+			}
+		}
+		resolved = append(resolved, database.SourcePath{Path: sourcePath, Patterns: patterns})
+	}
+	return resolved, nil
+}
+
+// newProvenance describes where the stored content of repo came from.
+func newProvenance(repo *database.Repository, fileCount int) *format.Provenance {
+	ref := repo.ResolvedRef
+	if ref == "" {
+		ref = repo.Branch
+	}
+	return &format.Provenance{
+		Repository: repo.URL,
+		Ref:        ref,
+		RefMode:    repo.RefMode,
+		Commit:     repo.LastCommitSHA,
+		SyncedAt:   repo.LastSyncTime.Time, // Zero if never synced
+		Files:      fileCount,
+	}
+}
+
+// filenamePart makes s safe for use in a download filename, replacing path separators and
+// other unsafe characters with underscores.
+func filenamePart(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '.' || r == '-' {
+			return r
+		}
+		return '_'
+	}, s)
+}
+
+// parsePagination reads the limit and offset query parameters, applying defaults and bounds.
+func parsePagination(c *gin.Context, defaultLimit, maxLimit int) (limit, offset int, err error) {
+	limit = defaultLimit
+	if v := c.Query("limit"); v != "" {
+		limit, err = strconv.Atoi(v)
+		if err != nil || limit < 1 {
+			return 0, 0, fmt.Errorf("limit must be a positive integer")
+		}
+		if limit > maxLimit {
+			limit = maxLimit
+		}
+	}
+	if v := c.Query("offset"); v != "" {
+		offset, err = strconv.Atoi(v)
+		if err != nil || offset < 0 {
+			return 0, 0, fmt.Errorf("offset must be a non-negative integer")
+		}
+	}
+	return limit, offset, nil
+}
+
+// ListSyncRunsHandler handles GET /api/repositories/:id/runs requests.
+// It returns the sync history of a repository, newest first, paginated with limit and offset.
+func (a *API) ListSyncRunsHandler(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid repository ID format"})
+		return
+	}
+
+	limit, offset, err := parsePagination(c, 20, 100)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid pagination: " + err.Error()})
+		return
+	}
+
+	// Check the repository exists so an unknown ID is a 404 rather than an empty list
+	_, err = a.Store.GetRepositoryByID(c.Request.Context(), id)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			c.JSON(http.StatusNotFound, ErrorResponse{Error: err.Error()})
+		} else {
+			log.Printf("Error finding repository %d before listing sync runs: %v", id, err)
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to find repository"})
+		}
+		return
+	}
+
+	runs, total, err := a.Store.ListSyncRuns(c.Request.Context(), id, limit, offset)
+	if err != nil {
+		log.Printf("Error listing sync runs for repository %d: %v", id, err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to retrieve sync runs"})
+		return
+	}
+
+	c.JSON(http.StatusOK, database.SyncRunList{Runs: runs, Total: total, Limit: limit, Offset: offset})
+}
+
+// ListSnapshotsHandler handles GET /api/repositories/:id/snapshots requests.
+// It returns snapshot metadata (without content), newest first, paginated with limit and offset.
+func (a *API) ListSnapshotsHandler(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid repository ID format"})
+		return
+	}
+
+	limit, offset, err := parsePagination(c, 20, 100)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid pagination: " + err.Error()})
+		return
+	}
+
+	_, err = a.Store.GetRepositoryByID(c.Request.Context(), id)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			c.JSON(http.StatusNotFound, ErrorResponse{Error: err.Error()})
+		} else {
+			log.Printf("Error finding repository %d before listing snapshots: %v", id, err)
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to find repository"})
+		}
+		return
+	}
+
+	snapshots, total, err := a.Store.ListSnapshots(c.Request.Context(), id, limit, offset)
+	if err != nil {
+		log.Printf("Error listing snapshots for repository %d: %v", id, err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to retrieve snapshots"})
+		return
+	}
+
+	c.JSON(http.StatusOK, database.SnapshotList{Snapshots: snapshots, Total: total, Limit: limit, Offset: offset})
+}
+
+// DownloadSnapshotHandler handles GET /api/repositories/:id/snapshots/:snapshotId/download requests.
+func (a *API) DownloadSnapshotHandler(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid repository ID format"})
+		return
+	}
+	snapshotID, err := strconv.Atoi(c.Param("snapshotId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid snapshot ID format"})
+		return
+	}
+
+	repo, err := a.Store.GetRepositoryByID(c.Request.Context(), id)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			c.JSON(http.StatusNotFound, ErrorResponse{Error: err.Error()})
+		} else {
+			log.Printf("Error getting repository %d for snapshot download: %v", id, err)
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to retrieve repository"})
+		}
+		return
+	}
+
+	snapshot, err := a.Store.GetSnapshot(c.Request.Context(), id, snapshotID)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			c.JSON(http.StatusNotFound, ErrorResponse{Error: err.Error()})
+		} else {
+			log.Printf("Error getting snapshot %d for repository %d: %v", snapshotID, id, err)
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to retrieve snapshot"})
+		}
+		return
+	}
+
+	filename := fmt.Sprintf("%s_%s_%s_docs_snapshot_%d.md", repo.RepoName, filenamePart(repo.RefLabel()), filenamePart(repo.DocsPath), snapshot.ID)
+	c.Header("Content-Disposition", "attachment; filename="+filename)
+	c.Header("Content-Type", "text/markdown; charset=utf-8")
+	c.Status(http.StatusOK)
+
+	if _, err := io.Copy(c.Writer, strings.NewReader(snapshot.Content)); err != nil {
+		log.Printf("Error streaming snapshot %d for repository %d: %v", snapshotID, id, err)
+	}
+}
+
+// DiffSnapshotsHandler handles GET /api/repositories/:id/diff?from=&to= requests.
+// It compares two snapshots of a repository file by file, returning added, removed and
+// modified files with a unified diff for each modified file.
+func (a *API) DiffSnapshotsHandler(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid repository ID format"})
+		return
+	}
+	fromID, err := strconv.Atoi(c.Query("from"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Query parameter 'from' must be a snapshot ID"})
+		return
+	}
+	toID, err := strconv.Atoi(c.Query("to"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Query parameter 'to' must be a snapshot ID"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	var snapshots [2]*database.Snapshot
+	var files [2][]database.RepositoryFile
+	for i, snapshotID := range []int{fromID, toID} {
+		snapshots[i], err = a.Store.GetSnapshot(ctx, id, snapshotID)
+		if err == nil {
+			files[i], err = a.Store.GetSnapshotFiles(ctx, id, snapshotID)
+		}
+		if err != nil {
+			if strings.Contains(err.Error(), "not found") {
+				c.JSON(http.StatusNotFound, ErrorResponse{Error: err.Error()})
+			} else {
+				log.Printf("Error loading snapshot %d of repository %d for diff: %v", snapshotID, id, err)
+				c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to retrieve snapshot"})
+			}
+			return
+		}
+	}
+
+	result := database.SnapshotDiff{
+		From:     *snapshots[0],
+		To:       *snapshots[1],
+		Added:    []string{},
+		Removed:  []string{},
+		Modified: []database.ModifiedFile{},
+	}
+
+	fromFiles := make(map[string]database.RepositoryFile, len(files[0]))
+	for _, file := range files[0] {
+		fromFiles[file.Path] = file
+	}
+	toPaths := make(map[string]bool, len(files[1]))
+	for _, file := range files[1] { // Files are ordered by path, so results are too
+		toPaths[file.Path] = true
+		old, ok := fromFiles[file.Path]
+		if !ok {
+			result.Added = append(result.Added, file.Path)
+			continue
+		}
+		if old.SHA != file.SHA || old.Content != file.Content {
+			result.Modified = append(result.Modified, database.ModifiedFile{
+				Path: file.Path,
+				Diff: diff.Unified("a/"+file.Path, "b/"+file.Path, old.Content, file.Content, 3),
+			})
+		}
+	}
+	for _, file := range files[0] {
+		if !toPaths[file.Path] {
+			result.Removed = append(result.Removed, file.Path)
+		}
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// --- Job Handlers ---
+
+// ListSyncJobsHandler handles GET /api/jobs requests.
+// Jobs can be filtered with the repository_id and status query parameters and are
+// paginated with limit and offset, newest first.
+func (a *API) ListSyncJobsHandler(c *gin.Context) {
+	var filter database.SyncJobFilter
+	if v := c.Query("repository_id"); v != "" {
+		repoID, err := strconv.Atoi(v)
 		if err != nil {
-			// Error is logged within SyncRepositoryByID, but maybe log completion status here too.
-			log.Printf("Background sync for repo ID %d finished with error: %v", id, err)
+			c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid repository ID format"})
+			return
+		}
+		filter.RepositoryID = repoID
+	}
+	filter.Status = c.Query("status")
+
+	limit, offset, err := parsePagination(c, 50, 200)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid pagination: " + err.Error()})
+		return
+	}
+
+	jobs, total, err := a.Store.ListSyncJobs(c.Request.Context(), filter, limit, offset)
+	if err != nil {
+		log.Printf("Error listing sync jobs: %v", err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to retrieve sync jobs"})
+		return
+	}
+
+	c.JSON(http.StatusOK, database.SyncJobList{Jobs: jobs, Total: total, Limit: limit, Offset: offset})
+}
+
+// GetSyncJobHandler handles GET /api/jobs/:id requests.
+func (a *API) GetSyncJobHandler(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid job ID format"})
+		return
+	}
+
+	job, err := a.Store.GetSyncJob(c.Request.Context(), id)
+	if err != nil {
+		if strings.Contains(err.Error(), "not found") {
+			c.JSON(http.StatusNotFound, ErrorResponse{Error: err.Error()})
 		} else {
-			log.Printf("Background sync for repo ID %d finished successfully.", id)
+			log.Printf("Error getting sync job %d: %v", id, err)
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to retrieve sync job"})
 		}
-	}()
+		return
+	}
 
-	// Return Accepted immediately
-	c.JSON(http.StatusAccepted, gin.H{"message": fmt.Sprintf("Sync initiated for repository %d. Status will be updated.", id)})
+	c.JSON(http.StatusOK, job)
+}
+
+// CancelSyncJobHandler handles POST /api/jobs/:id/cancel requests.
+// Only queued jobs can be cancelled.
+func (a *API) CancelSyncJobHandler(c *gin.Context) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid job ID format"})
+		return
+	}
+
+	job, err := a.Store.CancelSyncJob(c.Request.Context(), id)
+	if err != nil {
+		switch {
+		case strings.Contains(err.Error(), "not found"):
+			c.JSON(http.StatusNotFound, ErrorResponse{Error: err.Error()})
+		case strings.Contains(err.Error(), "cannot be cancelled"):
+			c.JSON(http.StatusConflict, ErrorResponse{Error: err.Error()})
+		default:
+			log.Printf("Error cancelling sync job %d: %v", id, err)
+			c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to cancel sync job"})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, job)
+}
+
+// --- System Handlers ---
+
+// GetRateLimitHandler handles GET /api/github/rate-limit requests.
+// It reports the GitHub API budget as last seen on API responses, without spending a request.
+func (a *API) GetRateLimitHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, a.GithubClient.RateLimitStatus())
 }
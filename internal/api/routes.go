@@ -7,27 +7,51 @@ import (
 	"syncdocs/internal/database"
 	gh "syncdocs/internal/github" // Import github client
 	"syncdocs/internal/syncer"   // Import syncer
+	"syncdocs/internal/tasks"
 )
 
 // RegisterRoutes sets up the API routes for the application.
-func RegisterRoutes(router *gin.RouterGroup, store *database.RepositoryStore, syncer *syncer.Syncer, githubClient *gh.Client) { // Add githubClient parameter
+func RegisterRoutes(router *gin.RouterGroup, store *database.RepositoryStore, syncer *syncer.Syncer, queue *tasks.Queue, scheduler *tasks.Scheduler, githubClient *gh.Client) { // Add githubClient parameter
 	// Create API instance with dependencies
-	apiHandler := NewAPI(store, syncer, githubClient) // Pass syncer and githubClient
+	apiHandler := NewAPI(store, syncer, queue, scheduler, githubClient) // Pass syncer, queue, scheduler and githubClient
 
 	// Repository routes
 	repoRoutes := router.Group("/repositories")
 	{
 		repoRoutes.POST("", apiHandler.CreateRepositoryHandler)       // Add new repository
+		repoRoutes.POST("/bulk", apiHandler.BulkCreateRepositoriesHandler) // Add one repository entry per ref
 		repoRoutes.GET("", apiHandler.ListRepositoriesHandler)        // List all repositories
 		repoRoutes.GET("/:id", apiHandler.GetRepositoryHandler)       // Get details of one repository (incl. content)
 		repoRoutes.PUT("/:id", apiHandler.UpdateRepositoryHandler)    // Update repository config
+		repoRoutes.PATCH("/:id", apiHandler.PatchRepositoryHandler)   // Partially update repository config, incl. URL and branch
 		repoRoutes.DELETE("/:id", apiHandler.DeleteRepositoryHandler) // Delete repository
 
 		// Actions for a specific repository
 		repoRoutes.POST("/:id/sync", apiHandler.TriggerSyncHandler) // Manually trigger sync
+		repoRoutes.POST("/:id/sync/cancel", apiHandler.CancelSyncHandler) // Cancel an in-flight sync
+		repoRoutes.GET("/:id/events", apiHandler.RepositoryEventsHandler) // Stream sync progress (SSE)
+		repoRoutes.GET("/:id/runs", apiHandler.ListSyncRunsHandler) // Sync run history (paginated)
 		// Apply gzip compression to the download route
 		repoRoutes.GET("/:id/download", gzip.Gzip(gzip.DefaultCompression), apiHandler.DownloadRepositoryContentHandler) // Download aggregated content
+		repoRoutes.GET("/:id/archive", apiHandler.DownloadRepositoryArchiveHandler) // Download the synced files as a zip or tar.gz (already compressed)
+		repoRoutes.GET("/:id/snapshots", apiHandler.ListSnapshotsHandler)                                                                // List content snapshots (paginated)
+		repoRoutes.GET("/:id/diff", apiHandler.DiffSnapshotsHandler)                                                                     // Diff two snapshots (?from=&to=)
+		repoRoutes.GET("/:id/snapshots/:snapshotId/download", gzip.Gzip(gzip.DefaultCompression), apiHandler.DownloadSnapshotHandler) // Download a snapshot
 	}
 
+	// Sync progress of all repositories (SSE)
+	router.GET("/events", apiHandler.EventsHandler)
+
+	// Sync job queue
+	jobRoutes := router.Group("/jobs")
+	{
+		jobRoutes.GET("", apiHandler.ListSyncJobsHandler)               // List jobs (?repository_id=&status=, paginated)
+		jobRoutes.GET("/:id", apiHandler.GetSyncJobHandler)             // Get one job
+		jobRoutes.POST("/:id/cancel", apiHandler.CancelSyncJobHandler) // Cancel a queued job
+	}
+
+	// GitHub API budget as tracked by the client
+	router.GET("/github/rate-limit", apiHandler.GetRateLimitHandler)
+
 	// Add other routes here if needed (e.g., system status)
 }
@@ -0,0 +1,200 @@
+package api
+
+import (
+	"log"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/go-github/v62/github"
+
+	"syncdocs/internal/database"
+	"syncdocs/internal/pathfilter"
+	"syncdocs/internal/syncer"
+	"syncdocs/internal/tasks"
+)
+
+// WebhookHandler receives GitHub webhook deliveries and triggers targeted syncs.
+type WebhookHandler struct {
+	Store  *database.RepositoryStore
+	Queue  *tasks.Queue
+	secret []byte // Shared secret used to verify X-Hub-Signature-256
+}
+
+// NewWebhookHandler creates a new WebhookHandler verifying deliveries with the given secret.
+func NewWebhookHandler(store *database.RepositoryStore, queue *tasks.Queue, secret string) *WebhookHandler {
+	return &WebhookHandler{
+		Store:  store,
+		Queue:  queue,
+		secret: []byte(secret),
+	}
+}
+
+// RegisterWebhookRoutes sets up the webhook routes. They must not sit behind Basic Auth:
+// deliveries are authenticated by their HMAC signature instead.
+func RegisterWebhookRoutes(router *gin.RouterGroup, store *database.RepositoryStore, queue *tasks.Queue, secret string) {
+	handler := NewWebhookHandler(store, queue, secret)
+	router.POST("/github", handler.GitHubWebhookHandler)
+}
+
+// GitHubWebhookHandler handles POST /webhooks/github requests.
+// Push events trigger a sync of every registered repository following the pushed branch
+// whose source paths contain at least one of the changed files, and of every repository
+// following semver tags that the pushed tag matches. Release events trigger a sync of the
+// repositories following the latest release.
+func (h *WebhookHandler) GitHubWebhookHandler(c *gin.Context) {
+	payload, err := github.ValidatePayload(c.Request, h.secret)
+	if err != nil {
+		log.Printf("Rejected GitHub webhook delivery: %v", err)
+		c.JSON(http.StatusUnauthorized, ErrorResponse{Error: "Invalid webhook signature"})
+		return
+	}
+
+	eventType := github.WebHookType(c.Request)
+	event, err := github.ParseWebHook(eventType, payload)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Invalid webhook payload: " + err.Error()})
+		return
+	}
+
+	switch e := event.(type) {
+	case *github.PingEvent:
+		c.JSON(http.StatusOK, gin.H{"message": "pong"})
+	case *github.PushEvent:
+		h.handlePush(c, e)
+	case *github.ReleaseEvent:
+		h.handleRelease(c, e)
+	default:
+		// Acknowledge other events so GitHub does not report failed deliveries
+		c.JSON(http.StatusOK, gin.H{"message": "Event ignored: " + eventType})
+	}
+}
+
+// handlePush matches a push event to registered repositories and triggers their syncs.
+func (h *WebhookHandler) handlePush(c *gin.Context, event *github.PushEvent) {
+	if event.GetDeleted() {
+		c.JSON(http.StatusOK, gin.H{"message": "Ref deletion ignored"})
+		return
+	}
+
+	var match func(repo database.Repository) bool
+	if tag, ok := strings.CutPrefix(event.GetRef(), "refs/tags/"); ok {
+		// A new tag may be the one a semver ref mode now selects
+		match = func(repo database.Repository) bool {
+			_, ok := syncer.LatestSemverTag([]string{tag}, repo.RefPattern)
+			return repo.RefMode == database.RefModeSemver && ok
+		}
+	} else if branch, ok := strings.CutPrefix(event.GetRef(), "refs/heads/"); ok {
+		changedPaths, complete := pushChangedPaths(event)
+		match = func(repo database.Repository) bool {
+			if repo.RefMode != database.RefModeBranch {
+				return false
+			}
+			repoBranch := repo.Branch
+			if repoBranch == "" {
+				repoBranch = event.GetRepo().GetDefaultBranch() // Repositories without a stored branch follow the default
+			}
+			return repoBranch == branch && pushAffectsSources(changedPaths, complete, repo.EffectiveSourcePaths())
+		}
+	} else {
+		c.JSON(http.StatusOK, gin.H{"message": "Push to unsupported ref ignored"})
+		return
+	}
+
+	h.triggerSyncs(c, event.GetRepo().GetFullName(), event.GetRef(), match)
+}
+
+// handleRelease triggers the syncs of the repositories following the latest release when
+// a release is published (or a prerelease is promoted to a release).
+func (h *WebhookHandler) handleRelease(c *gin.Context, event *github.ReleaseEvent) {
+	if event.GetAction() != "released" {
+		c.JSON(http.StatusOK, gin.H{"message": "Release action ignored: " + event.GetAction()})
+		return
+	}
+	h.triggerSyncs(c, event.GetRepo().GetFullName(), event.GetRelease().GetTagName(), func(repo database.Repository) bool {
+		return repo.RefMode == database.RefModeLatestRelease
+	})
+}
+
+// triggerSyncs enqueues a sync of every registered repository of the GitHub repository
+// fullName ("owner/repo") accepted by match. ref describes the event for logging.
+func (h *WebhookHandler) triggerSyncs(c *gin.Context, fullName, ref string, match func(repo database.Repository) bool) {
+	owner, repoName, ok := strings.Cut(fullName, "/")
+	if !ok {
+		c.JSON(http.StatusBadRequest, ErrorResponse{Error: "Event is missing the repository name"})
+		return
+	}
+
+	repos, err := h.Store.FindRepositoriesByGitHubRepo(c.Request.Context(), owner, repoName)
+	if err != nil {
+		log.Printf("Error matching event for %s/%s against repositories: %v", owner, repoName, err)
+		c.JSON(http.StatusInternalServerError, ErrorResponse{Error: "Failed to match repositories"})
+		return
+	}
+
+	triggered := []int{}
+	for _, repo := range repos {
+		if !match(repo) {
+			continue
+		}
+		if _, err := h.Queue.Enqueue(c.Request.Context(), repo.ID, syncer.TriggerWebhook); err != nil {
+			log.Printf("Error enqueuing webhook sync for repo ID %d: %v", repo.ID, err)
+			continue
+		}
+		triggered = append(triggered, repo.ID)
+	}
+
+	log.Printf("Webhook for %s/%s (ref: %s) triggered syncs for repositories %v", owner, repoName, ref, triggered)
+	c.JSON(http.StatusAccepted, gin.H{"triggered": triggered})
+}
+
+// maxPushCommits is the number of commits GitHub lists at most in a push webhook payload.
+const maxPushCommits = 20
+
+// pushChangedPaths collects the files added, modified or removed by a push.
+// complete is false when the payload lists no commits, or as many as GitHub lists at most
+// (webhook payloads carry no total, so the list may have been cut), in which case changed
+// paths may be missing.
+func pushChangedPaths(event *github.PushEvent) (paths []string, complete bool) {
+	for _, commit := range event.Commits {
+		paths = append(paths, commit.Added...)
+		paths = append(paths, commit.Modified...)
+		paths = append(paths, commit.Removed...)
+	}
+	complete = len(event.Commits) > 0 && len(event.Commits) < maxPushCommits && event.GetSize() <= len(event.Commits)
+	return paths, complete
+}
+
+// pushAffectsSources reports whether a push changing paths requires a sync of the given
+// source paths. Without the full list of changed files (complete is false) the docs cannot
+// be ruled out, so it does. The root ignore file applies to every source path, even when
+// outside all of them.
+func pushAffectsSources(paths []string, complete bool, sources []database.SourcePath) bool {
+	return !complete || anyPathUnderSources(paths, sources) || slices.Contains(paths, pathfilter.IgnoreFileName)
+}
+
+// anyPathUnderSources reports whether any of paths lies within one of the source paths.
+func anyPathUnderSources(paths []string, sources []database.SourcePath) bool {
+	for _, source := range sources {
+		if anyPathUnder(paths, source.Path) {
+			return true
+		}
+	}
+	return false
+}
+
+// anyPathUnder reports whether any of paths lies within docsPath.
+// An empty or root docs path contains every path.
+func anyPathUnder(paths []string, docsPath string) bool {
+	docsPath = strings.Trim(docsPath, "/")
+	if docsPath == "" || docsPath == "." {
+		return len(paths) > 0
+	}
+	for _, path := range paths {
+		if path == docsPath || strings.HasPrefix(path, docsPath+"/") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,134 @@
+package api
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/go-github/v62/github"
+
+	"syncdocs/internal/database"
+)
+
+// pushEvent builds a push event whose commits each change the given paths.
+func pushEvent(size int, commits ...[]string) *github.PushEvent {
+	event := &github.PushEvent{Size: github.Int(size)}
+	for _, paths := range commits {
+		event.Commits = append(event.Commits, &github.HeadCommit{Modified: paths})
+	}
+	return event
+}
+
+func TestPushChangedPaths(t *testing.T) {
+	event := &github.PushEvent{
+		Size: github.Int(2),
+		Commits: []*github.HeadCommit{
+			{Added: []string{"docs/new.md"}, Modified: []string{"docs/index.md"}},
+			{Removed: []string{"docs/old.md"}},
+		},
+	}
+	paths, complete := pushChangedPaths(event)
+	if want := []string{"docs/new.md", "docs/index.md", "docs/old.md"}; !slices.Equal(paths, want) {
+		t.Errorf("paths = %v, want %v", paths, want)
+	}
+	if !complete {
+		t.Error("complete = false, want true")
+	}
+
+	tests := []struct {
+		name  string
+		event *github.PushEvent
+	}{
+		{"no commits", pushEvent(0)},
+		{"more commits than listed", pushEvent(25, []string{"a.md"})},
+		{"capped commit list", pushEvent(maxPushCommits, slices.Repeat([][]string{{"a.md"}}, maxPushCommits)...)},
+	}
+	for _, tt := range tests {
+		if _, complete := pushChangedPaths(tt.event); complete {
+			t.Errorf("%s: complete = true, want false", tt.name)
+		}
+	}
+	if _, complete := pushChangedPaths(pushEvent(maxPushCommits-1, slices.Repeat([][]string{{"a.md"}}, maxPushCommits-1)...)); !complete {
+		t.Errorf("%d commits: complete = false, want true", maxPushCommits-1)
+	}
+}
+
+func TestPushAffectsSources(t *testing.T) {
+	sources := func(paths ...string) []database.SourcePath {
+		result := make([]database.SourcePath, len(paths))
+		for i, p := range paths {
+			result[i] = database.SourcePath{Path: p}
+		}
+		return result
+	}
+
+	tests := []struct {
+		name     string
+		paths    []string
+		complete bool
+		sources  []database.SourcePath
+		want     bool
+	}{
+		{"change under docs path", []string{"docs/guide/setup.md"}, true, sources("docs"), true},
+		{"docs path itself", []string{"docs"}, true, sources("docs"), true},
+		{"docs path with slashes", []string{"docs/index.md"}, true, sources("/docs/"), true},
+		{"unrelated change", []string{"src/main.go", "docs-old/index.md"}, true, sources("docs"), false},
+		{"change under second source path", []string{"api/reference.md"}, true, sources("docs", "api"), true},
+		{"change outside all source paths", []string{"src/main.go"}, true, sources("docs", "api"), false},
+		{"repository root", []string{"src/main.go"}, true, sources(""), true},
+		{"root ignore file", []string{".syncdocsignore"}, true, sources("docs"), true},
+		{"nested ignore file outside sources", []string{"src/.syncdocsignore"}, true, sources("docs"), false},
+		{"incomplete payload", []string{"src/main.go"}, false, sources("docs"), true},
+		{"incomplete payload without paths", nil, false, sources("docs"), true},
+	}
+	for _, tt := range tests {
+		if got := pushAffectsSources(tt.paths, tt.complete, tt.sources); got != tt.want {
+			t.Errorf("%s: pushAffectsSources(%v, %v) = %v, want %v", tt.name, tt.paths, tt.complete, got, tt.want)
+		}
+	}
+}
+
+func TestGitHubWebhookSignature(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	const secret = "webhook-secret"
+	const body = `{"zen":"Keep it logically awesome.","hook_id":1}`
+	sign := func(key string) string {
+		mac := hmac.New(sha256.New, []byte(key))
+		mac.Write([]byte(body))
+		return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	}
+
+	tests := []struct {
+		name      string
+		signature string
+		want      int
+	}{
+		{"valid signature", sign(secret), http.StatusOK},
+		{"wrong secret", sign("other-secret"), http.StatusUnauthorized},
+		{"malformed signature", "sha256=not-hex", http.StatusUnauthorized},
+		{"missing signature", "", http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		router := gin.New()
+		// Store and queue are unused: deliveries are rejected before matching, and pings are answered directly
+		router.POST("/webhooks/github", NewWebhookHandler(nil, nil, secret).GitHubWebhookHandler)
+
+		req := httptest.NewRequest(http.MethodPost, "/webhooks/github", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-GitHub-Event", "ping")
+		if tt.signature != "" {
+			req.Header.Set("X-Hub-Signature-256", tt.signature)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		if w.Code != tt.want {
+			t.Errorf("%s: status = %d, want %d (body %s)", tt.name, w.Code, tt.want, w.Body.String())
+		}
+	}
+}
@@ -11,26 +11,50 @@ import (
 
 // Config holds all configuration for the application.
 type Config struct {
-	ServerPort    string
-	AuthUser      string
-	AuthPass      string
-	DatabaseURL   string
-	GithubToken   string
-	SyncInterval  time.Duration
+	ServerPort   string
+	AuthUser     string
+	AuthPass     string
+	DatabaseURL  string
+	GithubToken  string
+	SyncInterval time.Duration
+	MaxFileSize  int // Per-file size cap in bytes; larger files are skipped. 0 disables the cap.
+
+	// Snapshot retention: keep the last SnapshotKeepLast snapshots and/or those newer than
+	// SnapshotMaxAge. A zero value disables that rule. The latest snapshot is always kept.
+	SnapshotKeepLast int
+	SnapshotMaxAge   time.Duration
+
+	// GithubWebhookSecret verifies GitHub webhook deliveries; the webhook endpoint is disabled when empty.
+	GithubWebhookSecret string
+
+	// Sync job queue: number of concurrent workers and attempts per job before it fails.
+	SyncWorkers        int
+	SyncJobMaxAttempts int
+
+	// SyncLease is how long a sync may go without a heartbeat before it is considered dead
+	// and recovered. Running syncs refresh their heartbeat several times per lease.
+	SyncLease time.Duration
 }
 
 // LoadConfig loads configuration from environment variables.
 // It attempts to load a .env file first for local development.
 func LoadConfig() (*Config, error) {
 	// Attempt to load .env file, ignore error if it doesn't exist
-	_ = godotenv.Load() 
+	_ = godotenv.Load()
 
 	port := getEnv("SERVER_PORT", "8080")
-	authUser := getEnv("AUTH_USER", "") // Require AUTH_USER
-	authPass := getEnv("AUTH_PASS", "") // Require AUTH_PASS
-	dbURL := getEnv("DATABASE_URL", "") // Require DATABASE_URL
-	githubToken := getEnv("GITHUB_TOKEN", "") // Require GITHUB_TOKEN
-	syncIntervalStr := getEnv("SYNC_INTERVAL", "1h") // Default to 1 hour
+	authUser := getEnv("AUTH_USER", "")                       // Require AUTH_USER
+	authPass := getEnv("AUTH_PASS", "")                       // Require AUTH_PASS
+	dbURL := getEnv("DATABASE_URL", "")                       // Require DATABASE_URL
+	githubToken := getEnv("GITHUB_TOKEN", "")                 // Require GITHUB_TOKEN
+	webhookSecret := getEnv("GITHUB_WEBHOOK_SECRET", "")      // Optional: enables /webhooks/github
+	syncIntervalStr := getEnv("SYNC_INTERVAL", "1h")          // Default to 1 hour
+	maxFileSize := getEnvAsInt("MAX_FILE_SIZE", 10*1024*1024) // Default to 10 MB
+	snapshotKeepLast := getEnvAsInt("SNAPSHOT_KEEP_LAST", 30) // Default to the last 30 snapshots
+	snapshotKeepDays := getEnvAsInt("SNAPSHOT_KEEP_DAYS", 0)  // Default to no age limit
+	syncWorkers := getEnvAsInt("SYNC_WORKERS", 5)             // Default to 5 concurrent syncs
+	syncJobMaxAttempts := getEnvAsInt("SYNC_JOB_MAX_ATTEMPTS", 3)
+	syncLeaseStr := getEnv("SYNC_LEASE", "2m") // Default to 2 minutes
 
 	if authUser == "" || authPass == "" {
 		log.Fatal("AUTH_USER and AUTH_PASS environment variables are required")
@@ -42,25 +66,56 @@ func LoadConfig() (*Config, error) {
 		log.Fatal("GITHUB_TOKEN environment variable is required")
 	}
 
+	if syncWorkers < 1 {
+		log.Printf("Warning: Invalid SYNC_WORKERS value %d. Using 1.", syncWorkers)
+		syncWorkers = 1
+	}
+	if syncJobMaxAttempts < 1 {
+		log.Printf("Warning: Invalid SYNC_JOB_MAX_ATTEMPTS value %d. Using 1.", syncJobMaxAttempts)
+		syncJobMaxAttempts = 1
+	}
+
 	syncInterval, err := time.ParseDuration(syncIntervalStr)
 	if err != nil {
 		log.Printf("Warning: Invalid SYNC_INTERVAL format '%s'. Using default 1h. Error: %v", syncIntervalStr, err)
 		syncInterval = time.Hour // Default to 1 hour on parse error
 	}
 
+	syncLease, err := time.ParseDuration(syncLeaseStr)
+	if err != nil || syncLease < 10*time.Second {
+		log.Printf("Warning: Invalid SYNC_LEASE '%s' (minimum 10s). Using default 2m.", syncLeaseStr)
+		syncLease = 2 * time.Minute
+	}
+
 	cfg := &Config{
-		ServerPort:    port,
-		AuthUser:      authUser,
-		AuthPass:      authPass,
-		DatabaseURL:   dbURL,
-		GithubToken:   githubToken,
-		SyncInterval:  syncInterval,
+		ServerPort:   port,
+		AuthUser:     authUser,
+		AuthPass:     authPass,
+		DatabaseURL:  dbURL,
+		GithubToken:  githubToken,
+		SyncInterval: syncInterval,
+		MaxFileSize:  maxFileSize,
+
+		SnapshotKeepLast: snapshotKeepLast,
+		SnapshotMaxAge:   time.Duration(snapshotKeepDays) * 24 * time.Hour,
+
+		GithubWebhookSecret: webhookSecret,
+
+		SyncWorkers:        syncWorkers,
+		SyncJobMaxAttempts: syncJobMaxAttempts,
+
+		SyncLease: syncLease,
 	}
 
 	log.Println("Configuration loaded successfully.")
 	// Avoid logging sensitive info like tokens or passwords in production
 	log.Printf("Server Port: %s", cfg.ServerPort)
 	log.Printf("Sync Interval: %s", cfg.SyncInterval.String())
+	log.Printf("GitHub Webhook: %t", cfg.GithubWebhookSecret != "")
+	log.Printf("Sync Workers: %d (max %d attempts per job)", cfg.SyncWorkers, cfg.SyncJobMaxAttempts)
+	log.Printf("Sync Lease: %s", cfg.SyncLease.String())
+	log.Printf("Max File Size: %d bytes", cfg.MaxFileSize)
+	log.Printf("Snapshot Retention: last %d, max age %s", cfg.SnapshotKeepLast, cfg.SnapshotMaxAge.String())
 
 	return cfg, nil
 }
@@ -74,7 +129,6 @@ func getEnv(key, fallback string) string {
 }
 
 // getEnvAsInt retrieves an environment variable as an integer or returns a default value.
-// Kept for potential future use, though not currently needed for this config.
 func getEnvAsInt(key string, fallback int) int {
 	valueStr := getEnv(key, "")
 	if value, err := strconv.Atoi(valueStr); err == nil {
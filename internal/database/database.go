@@ -15,13 +15,13 @@ import (
 const initSchemaSQL = `-- Create the repositories table
 CREATE TABLE IF NOT EXISTS repositories (
     id SERIAL PRIMARY KEY,
-    url VARCHAR(255) NOT NULL UNIQUE,       -- GitHub 仓库 URL (e.g., https://github.com/owner/repo)
+    url VARCHAR(255) NOT NULL,              -- GitHub 仓库 URL (e.g., https://github.com/owner/repo)
     owner VARCHAR(255) NOT NULL,            -- 从 URL 解析出的 owner
     repo_name VARCHAR(255) NOT NULL,        -- 从 URL 解析出的 repo name
     docs_path VARCHAR(255) NOT NULL,        -- 文档目录路径
     extensions VARCHAR(100) NOT NULL,       -- 文件扩展名 (逗号分隔, e.g., "md,mdx")
     aggregated_content TEXT,                -- 合并后的文档内容
-    last_sync_status VARCHAR(50) DEFAULT 'pending', -- 同步状态: pending, success, failed, syncing
+    last_sync_status VARCHAR(50) DEFAULT 'pending', -- 同步状态: pending, success, partial, failed, syncing, cancelled
     last_sync_time TIMESTAMPTZ,             -- 上次成功同步时间
     last_sync_error TEXT,                   -- 上次同步错误信息
     created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
@@ -49,6 +49,144 @@ BEGIN
 END
 $$;
 
+-- Ref to sync (branch name, tag or commit SHA depending on ref_mode); NULL means the default branch
+ALTER TABLE repositories ADD COLUMN IF NOT EXISTS branch VARCHAR(255);
+
+-- Files left out of the last sync (e.g., over the size limit), as a JSON array of {path, reason}
+ALTER TABLE repositories ADD COLUMN IF NOT EXISTS skipped_files JSONB;
+
+-- When true, files that fail to fetch are skipped and the sync is marked 'partial' instead of 'failed'
+ALTER TABLE repositories ADD COLUMN IF NOT EXISTS allow_partial BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Upstream state at the last successful sync, used to skip syncs when nothing changed
+ALTER TABLE repositories ADD COLUMN IF NOT EXISTS last_commit_sha VARCHAR(64);
+ALTER TABLE repositories ADD COLUMN IF NOT EXISTS docs_tree_sha VARCHAR(64);
+
+-- Lease of an in-progress sync, refreshed periodically while last_sync_status is 'syncing'.
+-- A 'syncing' row with an expired heartbeat belongs to a process that died mid-sync.
+ALTER TABLE repositories ADD COLUMN IF NOT EXISTS sync_heartbeat_at TIMESTAMPTZ;
+-- Set to ask the instance running the sync to cancel it (checked with each heartbeat)
+ALTER TABLE repositories ADD COLUMN IF NOT EXISTS sync_cancel_requested BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Sync schedule: a cron expression, "@every <duration>" or "manual"; NULL uses the global SYNC_INTERVAL
+ALTER TABLE repositories ADD COLUMN IF NOT EXISTS schedule VARCHAR(100);
+
+-- Ordered include/exclude ("!" prefix) glob patterns replacing extensions; existing extension
+-- lists are migrated to "**/*.<ext>" once, when the column is added
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM information_schema.columns WHERE table_schema = current_schema() AND table_name = 'repositories' AND column_name = 'patterns') THEN
+        ALTER TABLE repositories ADD COLUMN patterns JSONB NOT NULL DEFAULT '[]'::jsonb;
+        UPDATE repositories
+        SET patterns = COALESCE((
+            SELECT jsonb_agg('**/*.' || ltrim(btrim(lower(ext)), '.') ORDER BY ord)
+            FROM unnest(string_to_array(extensions, ',')) WITH ORDINALITY AS e(ext, ord)
+            WHERE ltrim(btrim(ext), '.') <> ''
+        ), '[]'::jsonb);
+    END IF;
+END
+$$;
+
+-- Ordered source paths, each with optional patterns of its own; the existing docs_path
+-- becomes the first one when the column is added
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM information_schema.columns WHERE table_schema = current_schema() AND table_name = 'repositories' AND column_name = 'source_paths') THEN
+        ALTER TABLE repositories ADD COLUMN source_paths JSONB NOT NULL DEFAULT '[]'::jsonb;
+        UPDATE repositories
+        SET source_paths = jsonb_build_array(jsonb_build_object('path', docs_path));
+    END IF;
+END
+$$;
+
+-- Ref mode: branch, tag or commit (pinned), latest_release or semver (resolved at each sync).
+-- The branch column holds the branch name, tag or commit SHA.
+ALTER TABLE repositories ADD COLUMN IF NOT EXISTS ref_mode VARCHAR(20) NOT NULL DEFAULT 'branch';
+ALTER TABLE repositories ADD COLUMN IF NOT EXISTS ref_pattern VARCHAR(255); -- Tag glob for the semver ref mode
+ALTER TABLE repositories ADD COLUMN IF NOT EXISTS resolved_ref VARCHAR(255); -- Ref used by the last successful sync
+
+-- A repository can be registered once per ref and docs path (e.g., the docs of v1.x and v2.x)
+ALTER TABLE repositories DROP CONSTRAINT IF EXISTS repositories_url_key;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_repositories_url_ref_docs_path
+    ON repositories (url, ref_mode, COALESCE(branch, ''), COALESCE(ref_pattern, ''), docs_path);
+
+-- Per-file cache used for incremental syncs. Each row holds the blob SHA and
+-- content of one synced file so unchanged files can be reused without refetching.
+CREATE TABLE IF NOT EXISTS repository_files (
+    repository_id INTEGER NOT NULL REFERENCES repositories(id) ON DELETE CASCADE,
+    path TEXT NOT NULL,                     -- Path of the file in the repository
+    sha VARCHAR(64) NOT NULL,               -- Git blob SHA
+    content TEXT NOT NULL,                  -- File content
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (repository_id, path)
+);
+-- Order of the file in the aggregated content, used to render the other download formats
+ALTER TABLE repository_files ADD COLUMN IF NOT EXISTS position INTEGER NOT NULL DEFAULT 0;
+
+-- History of sync runs, one row per sync attempt
+CREATE TABLE IF NOT EXISTS sync_runs (
+    id SERIAL PRIMARY KEY,
+    repository_id INTEGER NOT NULL REFERENCES repositories(id) ON DELETE CASCADE,
+    triggered_by VARCHAR(50) NOT NULL,      -- Trigger: scheduled, manual, initial, webhook, recovery, update
+    status VARCHAR(50) NOT NULL,            -- Run status: running, success, partial, unchanged, failed, cancelled
+    started_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    finished_at TIMESTAMPTZ,
+    commit_sha VARCHAR(64),                 -- Commit SHA at the time of the sync
+    files_listed INTEGER NOT NULL DEFAULT 0,
+    files_fetched INTEGER NOT NULL DEFAULT 0,
+    files_reused INTEGER NOT NULL DEFAULT 0,
+    files_skipped INTEGER NOT NULL DEFAULT 0,
+    bytes_fetched BIGINT NOT NULL DEFAULT 0,
+    api_calls INTEGER NOT NULL DEFAULT 0,
+    error TEXT
+);
+CREATE INDEX IF NOT EXISTS idx_sync_runs_repository_started ON sync_runs(repository_id, started_at DESC);
+-- Rules of the .syncdocsignore files applied by the run and the number of files they excluded
+ALTER TABLE sync_runs ADD COLUMN IF NOT EXISTS ignore_rules JSONB NOT NULL DEFAULT '[]'::jsonb;
+ALTER TABLE sync_runs ADD COLUMN IF NOT EXISTS files_ignored INTEGER NOT NULL DEFAULT 0;
+-- Branch, tag or commit SHA the ref mode resolved to
+ALTER TABLE sync_runs ADD COLUMN IF NOT EXISTS ref VARCHAR(255);
+
+-- Immutable snapshots of the aggregated content, created when a sync changes it
+CREATE TABLE IF NOT EXISTS snapshots (
+    id SERIAL PRIMARY KEY,
+    repository_id INTEGER NOT NULL REFERENCES repositories(id) ON DELETE CASCADE,
+    content_hash CHAR(64) NOT NULL,         -- SHA-256 of the aggregated content
+    commit_sha VARCHAR(64),                 -- Commit SHA at the time of the sync
+    file_count INTEGER NOT NULL DEFAULT 0,
+    size INTEGER NOT NULL DEFAULT 0,        -- Size of the aggregated content in bytes
+    content TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+CREATE INDEX IF NOT EXISTS idx_snapshots_repository_created ON snapshots(repository_id, created_at DESC);
+
+-- Files contained in each snapshot, used for diffs between snapshots
+CREATE TABLE IF NOT EXISTS snapshot_files (
+    snapshot_id INTEGER NOT NULL REFERENCES snapshots(id) ON DELETE CASCADE,
+    path TEXT NOT NULL,
+    sha VARCHAR(64) NOT NULL,
+    content TEXT NOT NULL,
+    PRIMARY KEY (snapshot_id, path)
+);
+
+-- Durable queue of sync jobs, claimed by workers with FOR UPDATE SKIP LOCKED
+CREATE TABLE IF NOT EXISTS sync_jobs (
+    id SERIAL PRIMARY KEY,
+    repository_id INTEGER NOT NULL REFERENCES repositories(id) ON DELETE CASCADE,
+    triggered_by VARCHAR(50) NOT NULL,      -- Trigger: scheduled, manual, initial, webhook, recovery, update
+    status VARCHAR(50) NOT NULL DEFAULT 'queued', -- Job status: queued, running, succeeded, failed, cancelled
+    attempts INTEGER NOT NULL DEFAULT 0,
+    max_attempts INTEGER NOT NULL DEFAULT 3,
+    run_after TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP, -- Earliest time the job may run (used for retry backoff)
+    last_error TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    started_at TIMESTAMPTZ,
+    finished_at TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS idx_sync_jobs_queued ON sync_jobs(run_after, id) WHERE status = 'queued';
+-- At most one queued job per repository
+CREATE UNIQUE INDEX IF NOT EXISTS idx_sync_jobs_one_queued_per_repository ON sync_jobs(repository_id) WHERE status = 'queued';
+
 -- Add comments to columns for better understanding (optional, but good practice)
 -- These might fail if run multiple times but are generally safe with IF NOT EXISTS or similar checks implicitly handled by COMMENT ON
 -- COMMENT ON COLUMN repositories.url IS 'GitHub repository URL (e.g., https://github.com/owner/repo)';
@@ -63,7 +201,9 @@ $$;
 `
 
 // ConnectDB establishes a connection pool to the PostgreSQL database.
-func ConnectDB(databaseURL string) (*pgxpool.Pool, error) {
+// reservedConns is the number of connections that may be held for long periods
+// (e.g., for advisory locks); the pool is grown so regular queries still find a free one.
+func ConnectDB(databaseURL string, reservedConns int) (*pgxpool.Pool, error) {
 	log.Println("Connecting to database...")
 
 	config, err := pgxpool.ParseConfig(databaseURL)
@@ -76,6 +216,9 @@ func ConnectDB(databaseURL string) (*pgxpool.Pool, error) {
 	// config.MinConns = 2
 	// config.MaxConnLifetime = time.Hour
 	// config.MaxConnIdleTime = 30 * time.Minute
+	if minConns := int32(reservedConns) + 4; config.MaxConns < minConns {
+		config.MaxConns = minConns
+	}
 
 	pool, err := pgxpool.NewWithConfig(context.Background(), config)
 	if err != nil {
@@ -0,0 +1,121 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// --- Methods for the per-file cache ---
+
+// GetRepositoryFiles retrieves the cached files for a repository, keyed by path.
+func (s *RepositoryStore) GetRepositoryFiles(ctx context.Context, repoID int) (map[string]RepositoryFile, error) {
+	query := `
+		SELECT repository_id, path, sha, content
+		FROM repository_files
+		WHERE repository_id = $1
+	`
+	rows, err := s.db.Query(ctx, query, repoID)
+	if err != nil {
+		log.Printf("Error getting cached files for repo ID %d: %v", repoID, err)
+		return nil, fmt.Errorf("failed to get cached files: %w", err)
+	}
+	defer rows.Close()
+
+	files := make(map[string]RepositoryFile)
+	for rows.Next() {
+		var file RepositoryFile
+		if err := rows.Scan(&file.RepositoryID, &file.Path, &file.SHA, &file.Content); err != nil {
+			log.Printf("Error scanning cached file row for repo ID %d: %v", repoID, err)
+			continue // Skip problematic row; it will simply be refetched
+		}
+		files[file.Path] = file
+	}
+
+	if err = rows.Err(); err != nil {
+		log.Printf("Error after iterating cached file rows for repo ID %d: %v", repoID, err)
+		return nil, fmt.Errorf("failed during cached file iteration: %w", err)
+	}
+
+	return files, nil
+}
+
+// CountRepositoryFiles returns the number of files in the content of the last sync.
+func (s *RepositoryStore) CountRepositoryFiles(ctx context.Context, repoID int) (int, error) {
+	var count int
+	err := s.db.QueryRow(ctx, `SELECT COUNT(*) FROM repository_files WHERE repository_id = $1`, repoID).Scan(&count)
+	if err != nil {
+		log.Printf("Error counting cached files for repo ID %d: %v", repoID, err)
+		return 0, fmt.Errorf("failed to count cached files: %w", err)
+	}
+	return count, nil
+}
+
+// ListRepositoryFiles returns the files of the last sync in the order of the aggregated content.
+func (s *RepositoryStore) ListRepositoryFiles(ctx context.Context, repoID int) ([]RepositoryFile, error) {
+	query := `
+		SELECT repository_id, path, sha, content
+		FROM repository_files
+		WHERE repository_id = $1
+		ORDER BY position ASC, path ASC
+	`
+	rows, err := s.db.Query(ctx, query, repoID)
+	if err != nil {
+		log.Printf("Error listing files for repo ID %d: %v", repoID, err)
+		return nil, fmt.Errorf("failed to list files: %w", err)
+	}
+	defer rows.Close()
+
+	var files []RepositoryFile
+	for rows.Next() {
+		var file RepositoryFile
+		if err := rows.Scan(&file.RepositoryID, &file.Path, &file.SHA, &file.Content); err != nil {
+			log.Printf("Error scanning file row for repo ID %d: %v", repoID, err)
+			return nil, fmt.Errorf("failed to scan file: %w", err)
+		}
+		files = append(files, file)
+	}
+
+	if err = rows.Err(); err != nil {
+		log.Printf("Error after iterating file rows for repo ID %d: %v", repoID, err)
+		return nil, fmt.Errorf("failed during file iteration: %w", err)
+	}
+
+	return files, nil
+}
+
+// replaceRepositoryFiles replaces the cached files for a repository with the given set
+// within tx, remembering their order in the aggregated content. Files not present in the
+// set (e.g., deleted upstream) are removed from the cache.
+func replaceRepositoryFiles(ctx context.Context, tx pgx.Tx, repoID int, files []RepositoryFile) error {
+	paths := make([]string, 0, len(files))
+	for _, file := range files {
+		paths = append(paths, file.Path)
+	}
+
+	// Drop files that no longer exist upstream
+	_, err := tx.Exec(ctx, `DELETE FROM repository_files WHERE repository_id = $1 AND NOT (path = ANY($2))`, repoID, paths)
+	if err != nil {
+		log.Printf("Error pruning cached files for repo ID %d: %v", repoID, err)
+		return fmt.Errorf("failed to prune cached files: %w", err)
+	}
+
+	upsert := `
+		INSERT INTO repository_files (repository_id, path, sha, content, position, updated_at)
+		VALUES ($1, $2, $3, $4, $5, NOW())
+		ON CONFLICT (repository_id, path) DO UPDATE
+		SET sha = EXCLUDED.sha, content = EXCLUDED.content, position = EXCLUDED.position, updated_at = NOW()
+		WHERE repository_files.sha <> EXCLUDED.sha OR repository_files.position <> EXCLUDED.position
+	`
+	batch := &pgx.Batch{}
+	for i, file := range files {
+		batch.Queue(upsert, repoID, file.Path, file.SHA, file.Content, i)
+	}
+	if err := tx.SendBatch(ctx, batch).Close(); err != nil {
+		log.Printf("Error storing cached files for repo ID %d: %v", repoID, err)
+		return fmt.Errorf("failed to store cached files: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,253 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// --- Methods for the sync job queue ---
+
+// syncJobColumns lists the columns scanned into a SyncJob by scanSyncJob, in order.
+const syncJobColumns = `id, repository_id, triggered_by, status, attempts, max_attempts, run_after, COALESCE(last_error, ''), created_at, started_at, finished_at`
+
+// scanSyncJob scans a row selected with syncJobColumns into job.
+func scanSyncJob(row pgx.Row, job *SyncJob) error {
+	return row.Scan(
+		&job.ID,
+		&job.RepositoryID,
+		&job.Trigger,
+		&job.Status,
+		&job.Attempts,
+		&job.MaxAttempts,
+		&job.RunAfter,
+		&job.LastError,
+		&job.CreatedAt,
+		&job.StartedAt,
+		&job.FinishedAt,
+	)
+}
+
+// EnqueueSyncJob queues a sync of a repository. If a job for the repository is already
+// queued, that job is returned instead and created is false.
+func (s *RepositoryStore) EnqueueSyncJob(ctx context.Context, repoID int, trigger string, maxAttempts int) (job *SyncJob, created bool, err error) {
+	job = &SyncJob{}
+	err = scanSyncJob(s.db.QueryRow(ctx, `
+		INSERT INTO sync_jobs (repository_id, triggered_by, max_attempts)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (repository_id) WHERE status = 'queued' DO NOTHING
+		RETURNING `+syncJobColumns,
+		repoID, trigger, maxAttempts,
+	), job)
+	if err == nil {
+		return job, true, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		log.Printf("Error enqueuing sync job for repo ID %d: %v", repoID, err)
+		return nil, false, fmt.Errorf("failed to enqueue sync job: %w", err)
+	}
+
+	// A job is already queued for this repository
+	err = scanSyncJob(s.db.QueryRow(ctx, `
+		SELECT `+syncJobColumns+`
+		FROM sync_jobs
+		WHERE repository_id = $1 AND status = 'queued'
+	`, repoID), job)
+	if err != nil {
+		log.Printf("Error getting queued sync job for repo ID %d: %v", repoID, err)
+		return nil, false, fmt.Errorf("failed to get queued sync job: %w", err)
+	}
+	return job, false, nil
+}
+
+// ClaimSyncJob atomically claims the next due queued job and marks it running.
+// Concurrent workers (in any process) never claim the same job. It returns nil if no job is due.
+func (s *RepositoryStore) ClaimSyncJob(ctx context.Context) (*SyncJob, error) {
+	query := `
+		UPDATE sync_jobs
+		SET status = 'running', attempts = attempts + 1, started_at = NOW(), finished_at = NULL
+		WHERE id = (
+			SELECT id FROM sync_jobs
+			WHERE status = 'queued' AND run_after <= NOW()
+			ORDER BY run_after, id
+			FOR UPDATE SKIP LOCKED
+			LIMIT 1
+		)
+		RETURNING ` + syncJobColumns
+	var job SyncJob
+	err := scanSyncJob(s.db.QueryRow(ctx, query), &job)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, nil
+		}
+		log.Printf("Error claiming sync job: %v", err)
+		return nil, fmt.Errorf("failed to claim sync job: %w", err)
+	}
+	return &job, nil
+}
+
+// CompleteSyncJob marks a running job as succeeded.
+func (s *RepositoryStore) CompleteSyncJob(ctx context.Context, id int) error {
+	_, err := s.db.Exec(ctx, `
+		UPDATE sync_jobs
+		SET status = 'succeeded', last_error = NULL, finished_at = NOW()
+		WHERE id = $1 AND status = 'running'
+	`, id)
+	if err != nil {
+		log.Printf("Error completing sync job %d: %v", id, err)
+		return fmt.Errorf("failed to complete sync job: %w", err)
+	}
+	return nil
+}
+
+// MarkSyncJobCancelled marks a running job as cancelled, used when its sync was cancelled on request.
+func (s *RepositoryStore) MarkSyncJobCancelled(ctx context.Context, id int) error {
+	_, err := s.db.Exec(ctx, `
+		UPDATE sync_jobs
+		SET status = 'cancelled', finished_at = NOW()
+		WHERE id = $1 AND status = 'running'
+	`, id)
+	if err != nil {
+		log.Printf("Error marking sync job %d as cancelled: %v", id, err)
+		return fmt.Errorf("failed to mark sync job as cancelled: %w", err)
+	}
+	return nil
+}
+
+// FailSyncJob records a failed attempt of a running job. If retryAt is non-nil the job is
+// queued again to run no earlier than retryAt; otherwise it is marked as failed for good.
+func (s *RepositoryStore) FailSyncJob(ctx context.Context, id int, jobErr error, retryAt *time.Time) error {
+	var err error
+	if retryAt != nil {
+		_, err = s.db.Exec(ctx, `
+			UPDATE sync_jobs
+			SET status = 'queued', last_error = $1, run_after = $2
+			WHERE id = $3 AND status = 'running'
+		`, jobErr.Error(), *retryAt, id)
+	} else {
+		_, err = s.db.Exec(ctx, `
+			UPDATE sync_jobs
+			SET status = 'failed', last_error = $1, finished_at = NOW()
+			WHERE id = $2 AND status = 'running'
+		`, jobErr.Error(), id)
+	}
+	if err != nil {
+		// A unique violation means another job for the repository was queued meanwhile;
+		// that job supersedes this retry.
+		if retryAt != nil && isUniqueViolation(err) {
+			_, err = s.db.Exec(ctx, `
+				UPDATE sync_jobs
+				SET status = 'failed', last_error = $1, finished_at = NOW()
+				WHERE id = $2 AND status = 'running'
+			`, jobErr.Error(), id)
+		}
+		if err != nil {
+			log.Printf("Error recording failure of sync job %d: %v", id, err)
+			return fmt.Errorf("failed to record sync job failure: %w", err)
+		}
+	}
+	return nil
+}
+
+// ReleaseSyncJob returns a running job to the queue without counting the attempt, to run
+// no earlier than runAfter. It is used when a worker is interrupted (e.g., on shutdown)
+// before the job finished, or when the repository is locked by a sync elsewhere.
+func (s *RepositoryStore) ReleaseSyncJob(ctx context.Context, id int, runAfter time.Time) error {
+	_, err := s.db.Exec(ctx, `
+		UPDATE sync_jobs
+		SET status = 'queued', attempts = GREATEST(attempts - 1, 0), started_at = NULL, run_after = $2
+		WHERE id = $1 AND status = 'running'
+	`, id, runAfter)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return nil // Another job for the repository is already queued; let this one go
+		}
+		log.Printf("Error releasing sync job %d: %v", id, err)
+		return fmt.Errorf("failed to release sync job: %w", err)
+	}
+	return nil
+}
+
+// CancelSyncJob cancels a queued job. It returns an error if the job does not exist
+// or is no longer queued.
+func (s *RepositoryStore) CancelSyncJob(ctx context.Context, id int) (*SyncJob, error) {
+	var job SyncJob
+	err := scanSyncJob(s.db.QueryRow(ctx, `
+		UPDATE sync_jobs
+		SET status = 'cancelled', finished_at = NOW()
+		WHERE id = $1 AND status = 'queued'
+		RETURNING `+syncJobColumns, id), &job)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			existing, getErr := s.GetSyncJob(ctx, id)
+			if getErr != nil {
+				return nil, getErr
+			}
+			return nil, fmt.Errorf("sync job %d cannot be cancelled in status '%s'", id, existing.Status)
+		}
+		log.Printf("Error cancelling sync job %d: %v", id, err)
+		return nil, fmt.Errorf("failed to cancel sync job: %w", err)
+	}
+	return &job, nil
+}
+
+// GetSyncJob retrieves a single sync job by its ID.
+func (s *RepositoryStore) GetSyncJob(ctx context.Context, id int) (*SyncJob, error) {
+	var job SyncJob
+	err := scanSyncJob(s.db.QueryRow(ctx, `SELECT `+syncJobColumns+` FROM sync_jobs WHERE id = $1`, id), &job)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("sync job with ID %d not found", id)
+		}
+		log.Printf("Error getting sync job %d: %v", id, err)
+		return nil, fmt.Errorf("failed to get sync job: %w", err)
+	}
+	return &job, nil
+}
+
+// ListSyncJobs retrieves a page of sync jobs matching filter, newest first,
+// along with the total number of matching jobs.
+func (s *RepositoryStore) ListSyncJobs(ctx context.Context, filter SyncJobFilter, limit, offset int) ([]SyncJob, int, error) {
+	where := `WHERE ($1 = 0 OR repository_id = $1) AND ($2 = '' OR status = $2)`
+
+	var total int
+	err := s.db.QueryRow(ctx, `SELECT COUNT(*) FROM sync_jobs `+where, filter.RepositoryID, filter.Status).Scan(&total)
+	if err != nil {
+		log.Printf("Error counting sync jobs: %v", err)
+		return nil, 0, fmt.Errorf("failed to count sync jobs: %w", err)
+	}
+
+	rows, err := s.db.Query(ctx, `
+		SELECT `+syncJobColumns+`
+		FROM sync_jobs
+		`+where+`
+		ORDER BY created_at DESC, id DESC
+		LIMIT $3 OFFSET $4
+	`, filter.RepositoryID, filter.Status, limit, offset)
+	if err != nil {
+		log.Printf("Error listing sync jobs: %v", err)
+		return nil, 0, fmt.Errorf("failed to list sync jobs: %w", err)
+	}
+	defer rows.Close()
+
+	jobs := []SyncJob{}
+	for rows.Next() {
+		var job SyncJob
+		if err := scanSyncJob(rows, &job); err != nil {
+			log.Printf("Error scanning sync job row: %v", err)
+			continue // Skip problematic row
+		}
+		jobs = append(jobs, job)
+	}
+
+	if err = rows.Err(); err != nil {
+		log.Printf("Error after iterating sync job rows: %v", err)
+		return nil, 0, fmt.Errorf("failed during sync job iteration: %w", err)
+	}
+
+	return jobs, total, nil
+}
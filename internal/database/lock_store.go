@@ -0,0 +1,75 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// --- Methods for cross-process locking ---
+
+// Advisory lock classes, used as the first key of the two-key advisory lock functions.
+// The "SD" prefix keeps them apart from advisory locks other applications may take
+// in the same database.
+const (
+	lockClassRepository int32 = 0x53440001 // Second key: repository ID
+	lockClassScheduler  int32 = 0x53440002 // Second key: always 0
+)
+
+// AdvisoryLock is a PostgreSQL session-level advisory lock. It holds on to the pooled
+// connection it was taken on; the lock is released by Unlock, or by the server if the
+// connection is lost.
+type AdvisoryLock struct {
+	conn       *pgxpool.Conn
+	class, key int32
+}
+
+// TryLockRepository takes the lock on a repository's sync, shared by all instances
+// using the database. It returns nil without an error if another session holds it.
+func (s *RepositoryStore) TryLockRepository(ctx context.Context, repoID int) (*AdvisoryLock, error) {
+	return s.tryAdvisoryLock(ctx, lockClassRepository, int32(repoID))
+}
+
+// TryLockSchedulerLeader takes the scheduler leadership lock. It returns nil without an
+// error if another instance is the leader.
+func (s *RepositoryStore) TryLockSchedulerLeader(ctx context.Context) (*AdvisoryLock, error) {
+	return s.tryAdvisoryLock(ctx, lockClassScheduler, 0)
+}
+
+// tryAdvisoryLock acquires a dedicated connection and tries to take the lock on it.
+func (s *RepositoryStore) tryAdvisoryLock(ctx context.Context, class, key int32) (*AdvisoryLock, error) {
+	conn, err := s.db.Acquire(ctx)
+	if err != nil {
+		log.Printf("Error acquiring connection for advisory lock (%d, %d): %v", class, key, err)
+		return nil, fmt.Errorf("failed to acquire connection for lock: %w", err)
+	}
+
+	var locked bool
+	if err := conn.QueryRow(ctx, `SELECT pg_try_advisory_lock($1, $2)`, class, key).Scan(&locked); err != nil {
+		conn.Release()
+		log.Printf("Error taking advisory lock (%d, %d): %v", class, key, err)
+		return nil, fmt.Errorf("failed to take lock: %w", err)
+	}
+	if !locked {
+		conn.Release()
+		return nil, nil
+	}
+	return &AdvisoryLock{conn: conn, class: class, key: key}, nil
+}
+
+// Alive reports whether the connection holding the lock, and therefore the lock, is still up.
+func (l *AdvisoryLock) Alive(ctx context.Context) bool {
+	return l.conn.Ping(ctx) == nil
+}
+
+// Unlock releases the lock and returns its connection to the pool. If the unlock fails the
+// connection is closed instead, which releases the lock on the server.
+func (l *AdvisoryLock) Unlock(ctx context.Context) {
+	if _, err := l.conn.Exec(ctx, `SELECT pg_advisory_unlock($1, $2)`, l.class, l.key); err != nil {
+		log.Printf("Error releasing advisory lock (%d, %d): %v. Closing its connection.", l.class, l.key, err)
+		_ = l.conn.Conn().Close(ctx)
+	}
+	l.conn.Release()
+}
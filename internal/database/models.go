@@ -12,41 +12,263 @@ type Repository struct {
 	URL               string         `db:"url"`
 	Owner             string         `db:"owner"`
 	RepoName          string         `db:"repo_name"`
-	DocsPath          string         `db:"docs_path"`
-	Extensions        string         `db:"extensions"` // Comma-separated list
-	Branch            string         `db:"branch"`               // Branch to sync from
+	DocsPath          string         `db:"docs_path"`          // First source path, kept for compatibility
+	SourcePaths       []SourcePath   `db:"source_paths"`       // Ordered paths aggregated into the output
+	Extensions        string         `db:"extensions"`         // Comma-separated list (legacy; superseded by Patterns)
+	Patterns          []string       `db:"patterns"`           // Ordered include/exclude ("!") glob patterns selecting the files to sync
+	Branch            string         `db:"branch"`             // Branch, tag or commit SHA to sync from, depending on RefMode
+	RefMode           string         `db:"ref_mode"`           // How the synced ref is chosen (see the RefMode* constants)
+	RefPattern        string         `db:"ref_pattern"`        // Tag glob for RefModeSemver (e.g., "v1.*"); empty matches every tag
+	ResolvedRef       string         `db:"resolved_ref"`       // Ref synced by the last successful sync, e.g. the latest release tag
+	AllowPartial      bool           `db:"allow_partial"`      // Skip files that fail to fetch instead of failing the sync
+	Schedule          string         `db:"schedule"`           // Cron spec, "@every <duration>" or "manual"; empty uses SYNC_INTERVAL
 	AggregatedContent sql.NullString `db:"aggregated_content"` // Use sql.NullString for potentially NULL TEXT field
-	LastSyncStatus    string         `db:"last_sync_status"`   // e.g., pending, success, failed, syncing
+	LastSyncStatus    string         `db:"last_sync_status"`   // e.g., pending, success, partial, failed, syncing, cancelled
 	LastSyncTime      sql.NullTime   `db:"last_sync_time"`     // Use sql.NullTime for potentially NULL TIMESTAMPTZ
 	LastSyncError     sql.NullString `db:"last_sync_error"`    // Use sql.NullString for potentially NULL TEXT field
+	SkippedFiles      []SkippedFile  `db:"skipped_files"`      // Files left out of the last sync and why
+	LastCommitSHA     string         `db:"last_commit_sha"`    // Commit the stored content was synced from
+	DocsTreeSHA       string         `db:"docs_tree_sha"`      // Tree SHA of the source path(s) at the last successful sync
 	CreatedAt         time.Time      `db:"created_at"`
 	UpdatedAt         time.Time      `db:"updated_at"`
 }
 
+// Ref modes, selecting which ref of the repository is synced.
+const (
+	RefModeBranch        = "branch"         // Follow the branch in Branch
+	RefModeTag           = "tag"            // Pinned to the tag in Branch
+	RefModeCommit        = "commit"         // Pinned to the commit SHA in Branch
+	RefModeLatestRelease = "latest_release" // The tag of the latest GitHub release, resolved at each sync
+	RefModeSemver        = "semver"         // The highest semver tag matching RefPattern, resolved at each sync
+)
+
+// IsPinned reports whether the repository syncs a fixed tag or commit, which is only
+// synced again on request or after the repository is reconfigured.
+func (r *Repository) IsPinned() bool {
+	return r.RefMode == RefModeTag || r.RefMode == RefModeCommit
+}
+
+// RefLabel returns a short name of the synced version, telling apart entries of the same
+// repository: the branch or tag, an abbreviated commit SHA, or for the ref modes that follow
+// releases the tag resolved by the last sync (the ref mode or pattern until then).
+func (r *Repository) RefLabel() string {
+	switch r.RefMode {
+	case RefModeCommit:
+		if len(r.Branch) > 12 {
+			return r.Branch[:12]
+		}
+	case RefModeLatestRelease, RefModeSemver:
+		if r.ResolvedRef != "" {
+			return r.ResolvedRef
+		}
+		if r.RefPattern != "" {
+			return r.RefPattern
+		}
+		return r.RefMode
+	}
+	return r.Branch
+}
+
+// DisplayName returns the repository as "owner/repo@ref", with RefLabel as the ref.
+func (r *Repository) DisplayName() string {
+	return r.Owner + "/" + r.RepoName + "@" + r.RefLabel()
+}
+
+// SourcePath is a file or directory of the repository whose files are aggregated.
+// Its patterns, if any, replace the repository's patterns for the files under it.
+type SourcePath struct {
+	Path     string   `json:"path"` // Relative to the repository root; empty for the root itself
+	Patterns []string `json:"patterns,omitempty"`
+}
+
+// EffectiveSourcePaths returns the source paths of the repository, falling back to
+// DocsPath for repositories created before multiple source paths were supported.
+func (r *Repository) EffectiveSourcePaths() []SourcePath {
+	if len(r.SourcePaths) > 0 {
+		return r.SourcePaths
+	}
+	return []SourcePath{{Path: r.DocsPath}}
+}
+
+// SkippedFile records a file that was left out of a sync and the reason why.
+type SkippedFile struct {
+	Path   string `json:"path"`
+	Reason string `json:"reason"`
+}
+
 // RepositoryListItem represents a repository item for listing purposes,
 // omitting the potentially large aggregated content.
 type RepositoryListItem struct {
 	ID             int          `json:"id"`
+	Name           string       `json:"name"` // "owner/repo@ref", telling apart versions of the same repository
 	URL            string       `json:"url"`
 	DocsPath       string       `json:"docs_path"`
+	SourcePaths    []SourcePath `json:"source_paths"`
 	Extensions     string       `json:"extensions"`
-	Branch         string       `json:"branch,omitempty"`
+	Patterns       []string     `json:"patterns"`
+	Branch         string       `json:"branch,omitempty"` // Branch, tag or commit SHA, depending on ref_mode
+	RefMode        string       `json:"ref_mode"`
+	RefPattern     string       `json:"ref_pattern,omitempty"`
+	ResolvedRef    string       `json:"resolved_ref,omitempty"`
+	CommitSHA      string       `json:"commit_sha,omitempty"` // Commit the stored content was synced from
+	AllowPartial   bool         `json:"allow_partial"`
+	Schedule       string       `json:"schedule,omitempty"`
 	LastSyncStatus string       `json:"last_sync_status"`
-	LastSyncTime   sql.NullTime `json:"last_sync_time"` // Keep as sql.NullTime for JSON marshalling
+	LastSyncTime   sql.NullTime `json:"last_sync_time"`  // Keep as sql.NullTime for JSON marshalling
 	LastSyncError  string       `json:"last_sync_error"` // Convert NullString to string for simpler JSON
 	UpdatedAt      time.Time    `json:"updated_at"`
 }
 
 // RepositoryCreatePayload defines the structure for creating a new repository entry.
 type RepositoryCreatePayload struct {
-	URL        string `json:"url" binding:"required,url"`
-	DocsPath   string `json:"docs_path" binding:"required"`
-	Extensions string `json:"extensions" binding:"required"` // e.g., "md,mdx"
-	Branch     string `json:"branch,omitempty"`              // Optional: defaults to repo's default branch if not provided
+	URL          string       `json:"url" binding:"required,url"`
+	DocsPath     string       `json:"docs_path,omitempty"`     // Single source path; ignored when source_paths is set
+	SourcePaths  []SourcePath `json:"source_paths,omitempty"`  // Ordered source paths, each optionally with its own patterns
+	Extensions   string       `json:"extensions,omitempty"`    // e.g., "md,mdx"; used as "**/*.<ext>" patterns when patterns is empty
+	Patterns     []string     `json:"patterns,omitempty"`      // e.g., ["docs/**/*.md", "!docs/api/generated/**"]
+	Branch       string       `json:"branch,omitempty"`        // Optional: defaults to repo's default branch if not provided
+	RefMode      string       `json:"ref_mode,omitempty"`      // Optional: branch (default), tag, commit, latest_release or semver
+	Ref          string       `json:"ref,omitempty"`           // Tag name or commit SHA for the tag and commit ref modes
+	RefPattern   string       `json:"ref_pattern,omitempty"`   // Tag glob for the semver ref mode (e.g., "v1.*")
+	AllowPartial bool         `json:"allow_partial,omitempty"` // Optional: skip files that fail to fetch instead of failing the sync
+	Schedule     string       `json:"schedule,omitempty"`      // Optional: cron spec, "@every <duration>" or "manual"; defaults to SYNC_INTERVAL
+}
+
+// RepositoryBulkCreatePayload creates one repository entry per ref, all sharing the rest
+// of the configuration (e.g., to track the docs of several major versions).
+type RepositoryBulkCreatePayload struct {
+	RepositoryCreatePayload
+	Refs []string `json:"refs" binding:"required,min=1"` // Branches, tags or commit SHAs per ref_mode; tag globs for semver
 }
 
 // RepositoryUpdatePayload defines the structure for updating an existing repository entry.
+// Omitted fields keep their current value; see UpdateRepositoryHandler.
 type RepositoryUpdatePayload struct {
-	DocsPath   string `json:"docs_path" binding:"required"`
-	Extensions string `json:"extensions" binding:"required"`
+	DocsPath     string       `json:"docs_path,omitempty"`
+	SourcePaths  []SourcePath `json:"source_paths,omitempty"` // Takes precedence over docs_path
+	Extensions   string       `json:"extensions,omitempty"`
+	Patterns     []string     `json:"patterns,omitempty"` // Takes precedence over extensions
+	AllowPartial *bool        `json:"allow_partial,omitempty"`
+	Schedule     *string      `json:"schedule,omitempty"` // Empty resets to SYNC_INTERVAL
+}
+
+// RepositoryPatchPayload defines a partial update of a repository entry.
+// Omitted (nil) fields keep their current value.
+type RepositoryPatchPayload struct {
+	URL          *string       `json:"url,omitempty" binding:"omitempty,url"`
+	Branch       *string       `json:"branch,omitempty"` // Empty switches to the repository's default branch
+	RefMode      *string       `json:"ref_mode,omitempty"`
+	Ref          *string       `json:"ref,omitempty"` // Tag name or commit SHA for the tag and commit ref modes
+	RefPattern   *string       `json:"ref_pattern,omitempty"`
+	DocsPath     *string       `json:"docs_path,omitempty"`
+	SourcePaths  *[]SourcePath `json:"source_paths,omitempty"` // Takes precedence over docs_path
+	Extensions   *string       `json:"extensions,omitempty"`
+	Patterns     *[]string     `json:"patterns,omitempty"` // Takes precedence over extensions
+	AllowPartial *bool         `json:"allow_partial,omitempty"`
+	Schedule     *string       `json:"schedule,omitempty"` // Empty resets to SYNC_INTERVAL
+}
+
+// RepositoryFile is a cached copy of a single synced file.
+// Corresponds to the 'repository_files' table in the database.
+type RepositoryFile struct {
+	RepositoryID int    `db:"repository_id"`
+	Path         string `db:"path"`
+	SHA          string `db:"sha"` // Git blob SHA of the content
+	Content      string `db:"content"`
+}
+
+// SyncRun records a single synchronization attempt of a repository.
+// Corresponds to the 'sync_runs' table in the database.
+type SyncRun struct {
+	ID           int        `db:"id" json:"id"`
+	RepositoryID int        `db:"repository_id" json:"repository_id"`
+	Trigger      string     `db:"triggered_by" json:"trigger"` // scheduled, manual, initial, webhook, recovery, update
+	Status       string     `db:"status" json:"status"`        // running, success, partial, unchanged, failed, cancelled
+	StartedAt    time.Time  `db:"started_at" json:"started_at"`
+	FinishedAt   *time.Time `db:"finished_at" json:"finished_at"`
+	Ref          string     `db:"ref" json:"ref,omitempty"` // Branch, tag or commit SHA the ref mode resolved to
+	CommitSHA    string     `db:"commit_sha" json:"commit_sha,omitempty"`
+	FilesListed  int        `db:"files_listed" json:"files_listed"`
+	FilesFetched int        `db:"files_fetched" json:"files_fetched"`
+	FilesReused  int        `db:"files_reused" json:"files_reused"` // Unchanged files taken from the cache
+	FilesSkipped int        `db:"files_skipped" json:"files_skipped"`
+	FilesIgnored int        `db:"files_ignored" json:"files_ignored"` // Excluded by .syncdocsignore rules
+	IgnoreRules  []string   `db:"ignore_rules" json:"ignore_rules"`   // Effective .syncdocsignore rules, as "<file>: <rule>"
+	BytesFetched int64      `db:"bytes_fetched" json:"bytes_fetched"`
+	APICalls     int        `db:"api_calls" json:"api_calls"`
+	Error        string     `db:"error" json:"error,omitempty"`
+}
+
+// SyncRunList is a page of sync runs for a repository.
+type SyncRunList struct {
+	Runs   []SyncRun `json:"runs"`
+	Total  int       `json:"total"`
+	Limit  int       `json:"limit"`
+	Offset int       `json:"offset"`
+}
+
+// Snapshot is an immutable copy of the aggregated content produced by a sync.
+// Corresponds to the 'snapshots' table in the database.
+type Snapshot struct {
+	ID           int       `db:"id" json:"id"`
+	RepositoryID int       `db:"repository_id" json:"repository_id"`
+	ContentHash  string    `db:"content_hash" json:"content_hash"` // SHA-256 of the aggregated content
+	CommitSHA    string    `db:"commit_sha" json:"commit_sha,omitempty"`
+	FileCount    int       `db:"file_count" json:"file_count"`
+	Size         int       `db:"size" json:"size"` // Size of the aggregated content in bytes
+	Content      string    `db:"content" json:"-"` // Only loaded when downloading a snapshot
+	CreatedAt    time.Time `db:"created_at" json:"created_at"`
+}
+
+// SnapshotList is a page of snapshots for a repository.
+type SnapshotList struct {
+	Snapshots []Snapshot `json:"snapshots"`
+	Total     int        `json:"total"`
+	Limit     int        `json:"limit"`
+	Offset    int        `json:"offset"`
+}
+
+// SnapshotDiff describes the file-level differences between two snapshots.
+type SnapshotDiff struct {
+	From     Snapshot       `json:"from"`
+	To       Snapshot       `json:"to"`
+	Added    []string       `json:"added"`
+	Removed  []string       `json:"removed"`
+	Modified []ModifiedFile `json:"modified"`
+}
+
+// ModifiedFile is a file present in both snapshots of a diff with different content.
+type ModifiedFile struct {
+	Path string `json:"path"`
+	Diff string `json:"diff"` // Unified diff of the file content
+}
+
+// SyncJob is a queued request to sync a repository, processed by the worker pool.
+// Corresponds to the 'sync_jobs' table in the database.
+type SyncJob struct {
+	ID           int        `db:"id" json:"id"`
+	RepositoryID int        `db:"repository_id" json:"repository_id"`
+	Trigger      string     `db:"triggered_by" json:"trigger"` // scheduled, manual, initial, webhook, recovery, update
+	Status       string     `db:"status" json:"status"`        // queued, running, succeeded, failed, cancelled
+	Attempts     int        `db:"attempts" json:"attempts"`
+	MaxAttempts  int        `db:"max_attempts" json:"max_attempts"`
+	RunAfter     time.Time  `db:"run_after" json:"run_after"` // Earliest time the job may be claimed
+	LastError    string     `db:"last_error" json:"last_error,omitempty"`
+	CreatedAt    time.Time  `db:"created_at" json:"created_at"`
+	StartedAt    *time.Time `db:"started_at" json:"started_at"`
+	FinishedAt   *time.Time `db:"finished_at" json:"finished_at"`
+}
+
+// SyncJobFilter narrows down a job listing. Zero values match everything.
+type SyncJobFilter struct {
+	RepositoryID int
+	Status       string
+}
+
+// SyncJobList is a page of sync jobs.
+type SyncJobList struct {
+	Jobs   []SyncJob `json:"jobs"`
+	Total  int       `json:"total"`
+	Limit  int       `json:"limit"`
+	Offset int       `json:"offset"`
 }
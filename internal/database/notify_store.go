@@ -0,0 +1,48 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"log"
+)
+
+// --- Methods for relaying sync events between instances ---
+
+// syncEventsChannel is the NOTIFY channel sync progress events are relayed on.
+const syncEventsChannel = "syncdocs_sync_events"
+
+// NotifySyncEvent sends a sync event payload to every instance listening with
+// ListenSyncEvents, including this one. Payloads must be shorter than 8000 bytes.
+func (s *RepositoryStore) NotifySyncEvent(ctx context.Context, payload string) error {
+	if _, err := s.db.Exec(ctx, `SELECT pg_notify($1, $2)`, syncEventsChannel, payload); err != nil {
+		return fmt.Errorf("failed to notify sync event: %w", err)
+	}
+	return nil
+}
+
+// ListenSyncEvents listens for sync event payloads on a dedicated connection and calls
+// handle with each one, until ctx is done or the connection fails. The connection is closed
+// afterwards rather than returned to the pool still listening.
+func (s *RepositoryStore) ListenSyncEvents(ctx context.Context, handle func(payload string)) error {
+	conn, err := s.db.Acquire(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to acquire connection to listen for sync events: %w", err)
+	}
+	defer conn.Release()
+	defer func() {
+		if err := conn.Conn().Close(context.Background()); err != nil {
+			log.Printf("Error closing sync event listener connection: %v", err)
+		}
+	}()
+
+	if _, err := conn.Exec(ctx, "LISTEN "+syncEventsChannel); err != nil {
+		return fmt.Errorf("failed to listen for sync events: %w", err)
+	}
+	for {
+		notification, err := conn.Conn().WaitForNotification(ctx)
+		if err != nil {
+			return fmt.Errorf("failed waiting for sync events: %w", err)
+		}
+		handle(notification.Payload)
+	}
+}
@@ -16,6 +16,49 @@ import (
 	gh "syncdocs/internal/github" // Alias github package
 )
 
+// repositoryColumns lists the columns scanned into a Repository by scanRepository, in order.
+const repositoryColumns = `id, url, owner, repo_name, docs_path, source_paths, extensions, patterns, branch, ref_mode, COALESCE(ref_pattern, ''), COALESCE(resolved_ref, ''), allow_partial, COALESCE(schedule, ''), aggregated_content, last_sync_status, last_sync_time, last_sync_error, skipped_files, COALESCE(last_commit_sha, ''), COALESCE(docs_tree_sha, ''), created_at, updated_at`
+
+// scanRepository scans a row selected with repositoryColumns into repo.
+func scanRepository(row pgx.Row, repo *Repository) error {
+	return row.Scan(
+		&repo.ID,
+		&repo.URL,
+		&repo.Owner,
+		&repo.RepoName,
+		&repo.DocsPath,
+		&repo.SourcePaths,
+		&repo.Extensions,
+		&repo.Patterns,
+		&repo.Branch,
+		&repo.RefMode,
+		&repo.RefPattern,
+		&repo.ResolvedRef,
+		&repo.AllowPartial,
+		&repo.Schedule,
+		&repo.AggregatedContent,
+		&repo.LastSyncStatus,
+		&repo.LastSyncTime,
+		&repo.LastSyncError,
+		&repo.SkippedFiles,
+		&repo.LastCommitSHA,
+		&repo.DocsTreeSHA,
+		&repo.CreatedAt,
+		&repo.UpdatedAt,
+	)
+}
+
+// isUniqueViolation reports whether err is a PostgreSQL unique constraint violation.
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
+
+// duplicateRepositoryError describes a violation of the uniqueness of (url, ref, docs_path).
+func duplicateRepositoryError(url string, repo *Repository) error {
+	return fmt.Errorf("repository with URL '%s', ref '%s' and docs path '%s' already exists", url, repo.RefLabel(), repo.DocsPath)
+}
+
 // RepositoryStore handles database operations for repositories.
 type RepositoryStore struct {
 	db *pgxpool.Pool
@@ -26,9 +69,44 @@ func NewRepositoryStore(db *pgxpool.Pool) *RepositoryStore {
 	return &RepositoryStore{db: db}
 }
 
+// rowQuerier is implemented by both the connection pool and transactions.
+type rowQuerier interface {
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
 // CreateRepository inserts a new repository record into the database.
 // It parses the owner and repo name from the URL.
 func (s *RepositoryStore) CreateRepository(ctx context.Context, payload RepositoryCreatePayload, branchToStore string) (*Repository, error) {
+	return insertRepository(ctx, s.db, payload, branchToStore)
+}
+
+// CreateRepositories inserts several repository records in one transaction: either all of
+// them are created or none. The Branch of each payload is stored as given.
+func (s *RepositoryStore) CreateRepositories(ctx context.Context, payloads []RepositoryCreatePayload) ([]*Repository, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx) // No-op if the transaction was committed
+
+	repos := make([]*Repository, 0, len(payloads))
+	for _, payload := range payloads {
+		repo, err := insertRepository(ctx, tx, payload, payload.Branch)
+		if err != nil {
+			return nil, err
+		}
+		repos = append(repos, repo)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		log.Printf("Error committing creation of %d repositories: %v", len(payloads), err)
+		return nil, fmt.Errorf("failed to commit repository creation: %w", err)
+	}
+	return repos, nil
+}
+
+// insertRepository inserts a repository record with q, parsing the owner and repo name from the URL.
+func insertRepository(ctx context.Context, q rowQuerier, payload RepositoryCreatePayload, branchToStore string) (*Repository, error) {
 	owner, repoName, err := gh.ParseRepoURL(payload.URL)
 	if err != nil {
 		return nil, fmt.Errorf("failed to parse repository URL: %w", err)
@@ -38,39 +116,31 @@ func (s *RepositoryStore) CreateRepository(ctx context.Context, payload Reposito
 	extensions := strings.ToLower(strings.ReplaceAll(payload.Extensions, " ", ""))
 
 	query := `
-		INSERT INTO repositories (url, owner, repo_name, docs_path, extensions, branch, last_sync_status)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)
-		RETURNING id, url, owner, repo_name, docs_path, extensions, branch, aggregated_content, last_sync_status, last_sync_time, last_sync_error, created_at, updated_at
-	`
+		INSERT INTO repositories (url, owner, repo_name, docs_path, source_paths, extensions, patterns, branch, ref_mode, ref_pattern, allow_partial, schedule, last_sync_status)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, NULLIF($10, ''), $11, NULLIF($12, ''), $13)
+		RETURNING ` + repositoryColumns
 	var repo Repository
-	err = s.db.QueryRow(ctx, query,
+	row := q.QueryRow(ctx, query,
 		payload.URL,
 		owner,
 		repoName,
 		payload.DocsPath,
+		payload.SourcePaths,
 		extensions,
+		payload.Patterns,
 		branchToStore, // Use the determined branch
+		payload.RefMode,
+		payload.RefPattern,
+		payload.AllowPartial,
+		payload.Schedule,
 		"pending",   // Initial status
-	).Scan(
-		&repo.ID,
-		&repo.URL,
-		&repo.Owner,
-		&repo.RepoName,
-		&repo.DocsPath,
-		&repo.Extensions,
-		&repo.Branch,
-		&repo.AggregatedContent,
-		&repo.LastSyncStatus,
-		&repo.LastSyncTime,
-		&repo.LastSyncError,
-		&repo.CreatedAt,
-		&repo.UpdatedAt,
 	)
+	err = scanRepository(row, &repo)
 
 	if err != nil {
-		var pgErr *pgconn.PgError
-		if errors.As(err, &pgErr) && pgErr.Code == "23505" { // Unique violation
-			return nil, fmt.Errorf("repository with URL '%s' already exists", payload.URL)
+		if isUniqueViolation(err) {
+			duplicate := Repository{Branch: branchToStore, RefMode: payload.RefMode, RefPattern: payload.RefPattern, DocsPath: payload.DocsPath}
+			return nil, duplicateRepositoryError(payload.URL, &duplicate)
 		}
 		log.Printf("Error creating repository in DB: %v", err)
 		return nil, fmt.Errorf("failed to create repository: %w", err)
@@ -82,26 +152,12 @@ func (s *RepositoryStore) CreateRepository(ctx context.Context, payload Reposito
 // GetRepositoryByID retrieves a single repository by its ID.
 func (s *RepositoryStore) GetRepositoryByID(ctx context.Context, id int) (*Repository, error) {
 	query := `
-		SELECT id, url, owner, repo_name, docs_path, extensions, branch, aggregated_content, last_sync_status, last_sync_time, last_sync_error, created_at, updated_at
+		SELECT ` + repositoryColumns + `
 		FROM repositories
 		WHERE id = $1
 	`
 	var repo Repository
-	err := s.db.QueryRow(ctx, query, id).Scan(
-		&repo.ID,
-		&repo.URL,
-		&repo.Owner,
-		&repo.RepoName,
-		&repo.DocsPath,
-		&repo.Extensions,
-		&repo.Branch,
-		&repo.AggregatedContent,
-		&repo.LastSyncStatus,
-		&repo.LastSyncTime,
-		&repo.LastSyncError,
-		&repo.CreatedAt,
-		&repo.UpdatedAt,
-	)
+	err := scanRepository(s.db.QueryRow(ctx, query, id), &repo)
 
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
@@ -117,7 +173,7 @@ func (s *RepositoryStore) GetRepositoryByID(ctx context.Context, id int) (*Repos
 // ListRepositories retrieves a list of all repositories (without aggregated content).
 func (s *RepositoryStore) ListRepositories(ctx context.Context) ([]RepositoryListItem, error) {
 	query := `
-		SELECT id, url, docs_path, extensions, branch, last_sync_status, last_sync_time, last_sync_error, updated_at
+		SELECT id, url, owner, repo_name, docs_path, source_paths, extensions, patterns, branch, ref_mode, COALESCE(ref_pattern, ''), COALESCE(resolved_ref, ''), COALESCE(last_commit_sha, ''), allow_partial, COALESCE(schedule, ''), last_sync_status, last_sync_time, last_sync_error, updated_at
 		FROM repositories
 		ORDER BY created_at DESC
 	`
@@ -133,13 +189,24 @@ func (s *RepositoryStore) ListRepositories(ctx context.Context) ([]RepositoryLis
 		var item RepositoryListItem
 		var lastSyncError sql.NullString // Scan into NullString first
 		var branch sql.NullString        // Scan branch into NullString
+		var owner, repoName string
 
 		err := rows.Scan(
 			&item.ID,
 			&item.URL,
+			&owner,
+			&repoName,
 			&item.DocsPath,
+			&item.SourcePaths,
 			&item.Extensions,
+			&item.Patterns,
 			&branch, // Scan into sql.NullString
+			&item.RefMode,
+			&item.RefPattern,
+			&item.ResolvedRef,
+			&item.CommitSHA,
+			&item.AllowPartial,
+			&item.Schedule,
 			&item.LastSyncStatus,
 			&item.LastSyncTime,
 			&lastSyncError, // Scan into NullString
@@ -152,6 +219,8 @@ func (s *RepositoryStore) ListRepositories(ctx context.Context) ([]RepositoryLis
 		}
 		item.LastSyncError = lastSyncError.String // Convert NullString to string
 		item.Branch = branch.String              // Convert NullString to string for branch
+		ref := Repository{Owner: owner, RepoName: repoName, Branch: item.Branch, RefMode: item.RefMode, RefPattern: item.RefPattern, ResolvedRef: item.ResolvedRef}
+		item.Name = ref.DisplayName()
 		items = append(items, item)
 	}
 
@@ -163,43 +232,41 @@ func (s *RepositoryStore) ListRepositories(ctx context.Context) ([]RepositoryLis
 	return items, nil
 }
 
-// UpdateRepository updates the configuration of an existing repository.
-func (s *RepositoryStore) UpdateRepository(ctx context.Context, id int, payload RepositoryUpdatePayload) (*Repository, error) {
+// UpdateRepository updates the configuration of an existing repository. With resetSync,
+// the stored commit and tree SHAs are cleared so that the next sync runs in full.
+func (s *RepositoryStore) UpdateRepository(ctx context.Context, id int, payload RepositoryUpdatePayload, resetSync bool) (*Repository, error) {
 	// Normalize extensions
 	extensions := strings.ToLower(strings.ReplaceAll(payload.Extensions, " ", ""))
 
 	query := `
 		UPDATE repositories
-		SET docs_path = $1, extensions = $2, updated_at = $3
-		WHERE id = $4
-		RETURNING id, url, owner, repo_name, docs_path, extensions, branch, aggregated_content, last_sync_status, last_sync_time, last_sync_error, created_at, updated_at
-	`
+		SET docs_path = $1, source_paths = $2, extensions = $3, patterns = $4, allow_partial = COALESCE($5, allow_partial),
+		    schedule = CASE WHEN $6::text IS NULL THEN schedule ELSE NULLIF($6, '') END, updated_at = $7,
+		    last_commit_sha = CASE WHEN $8 THEN NULL ELSE last_commit_sha END,
+		    docs_tree_sha = CASE WHEN $8 THEN NULL ELSE docs_tree_sha END
+		WHERE id = $9
+		RETURNING ` + repositoryColumns
 	var repo Repository
-	err := s.db.QueryRow(ctx, query,
+	row := s.db.QueryRow(ctx, query,
 		payload.DocsPath,
+		payload.SourcePaths,
 		extensions,
+		payload.Patterns,
+		payload.AllowPartial,
+		payload.Schedule,
 		time.Now(), // Explicitly set updated_at, though trigger should handle it
+		resetSync,
 		id,
-	).Scan(
-		&repo.ID,
-		&repo.URL,
-		&repo.Owner,
-		&repo.RepoName,
-		&repo.DocsPath,
-		&repo.Extensions,
-		&repo.Branch,
-		&repo.AggregatedContent,
-		&repo.LastSyncStatus,
-		&repo.LastSyncTime,
-		&repo.LastSyncError,
-		&repo.CreatedAt,
-		&repo.UpdatedAt,
 	)
+	err := scanRepository(row, &repo)
 
 	if err != nil {
 		if errors.Is(err, pgx.ErrNoRows) {
 			return nil, fmt.Errorf("repository with ID %d not found for update", id)
 		}
+		if isUniqueViolation(err) {
+			return nil, fmt.Errorf("a repository with the same URL, ref and docs path '%s' already exists", payload.DocsPath)
+		}
 		log.Printf("Error updating repository ID %d: %v", id, err)
 		return nil, fmt.Errorf("failed to update repository: %w", err)
 	}
@@ -207,6 +274,53 @@ func (s *RepositoryStore) UpdateRepository(ctx context.Context, id int, payload
 	return &repo, nil
 }
 
+// SaveRepositorySettings writes the configuration fields of repo (URL, owner, repository
+// name, branch and ref mode, source paths, extensions, patterns, allow_partial and
+// schedule), as applied by a partial update. With resetSync, the stored commit and tree
+// SHAs are cleared so that the next sync runs in full.
+func (s *RepositoryStore) SaveRepositorySettings(ctx context.Context, repo *Repository, resetSync bool) (*Repository, error) {
+	query := `
+		UPDATE repositories
+		SET url = $1, owner = $2, repo_name = $3, branch = $4, ref_mode = $5, ref_pattern = NULLIF($6, ''), docs_path = $7, source_paths = $8,
+		    extensions = $9, patterns = $10, allow_partial = $11, schedule = NULLIF($12, ''), updated_at = $13,
+		    last_commit_sha = CASE WHEN $14 THEN NULL ELSE last_commit_sha END,
+		    docs_tree_sha = CASE WHEN $14 THEN NULL ELSE docs_tree_sha END
+		WHERE id = $15
+		RETURNING ` + repositoryColumns
+	var updated Repository
+	row := s.db.QueryRow(ctx, query,
+		repo.URL,
+		repo.Owner,
+		repo.RepoName,
+		repo.Branch,
+		repo.RefMode,
+		repo.RefPattern,
+		repo.DocsPath,
+		repo.SourcePaths,
+		strings.ToLower(strings.ReplaceAll(repo.Extensions, " ", "")),
+		repo.Patterns,
+		repo.AllowPartial,
+		repo.Schedule,
+		time.Now(),
+		resetSync,
+		repo.ID,
+	)
+	err := scanRepository(row, &updated)
+
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("repository with ID %d not found for update", repo.ID)
+		}
+		if isUniqueViolation(err) {
+			return nil, duplicateRepositoryError(repo.URL, repo)
+		}
+		log.Printf("Error saving settings of repository ID %d: %v", repo.ID, err)
+		return nil, fmt.Errorf("failed to update repository: %w", err)
+	}
+
+	return &updated, nil
+}
+
 // DeleteRepository removes a repository record from the database.
 func (s *RepositoryStore) DeleteRepository(ctx context.Context, id int) error {
 	query := `DELETE FROM repositories WHERE id = $1`
@@ -227,6 +341,8 @@ func (s *RepositoryStore) DeleteRepository(ctx context.Context, id int) error {
 // --- Methods for Syncer ---
 
 // UpdateSyncStatus updates the sync status and error message for a repository.
+// Setting the status to "syncing" also starts the sync lease (see RefreshSyncHeartbeat)
+// and clears any earlier cancellation request.
 func (s *RepositoryStore) UpdateSyncStatus(ctx context.Context, id int, status string, syncError error) error {
 	var errMsg sql.NullString
 	if syncError != nil {
@@ -235,7 +351,8 @@ func (s *RepositoryStore) UpdateSyncStatus(ctx context.Context, id int, status s
 
 	query := `
 		UPDATE repositories
-		SET last_sync_status = $1, last_sync_error = $2, updated_at = NOW()
+		SET last_sync_status = $1, last_sync_error = $2, updated_at = NOW(),
+		    sync_heartbeat_at = CASE WHEN $1 = 'syncing' THEN NOW() END, sync_cancel_requested = FALSE
 		WHERE id = $3
 	`
 	_, err := s.db.Exec(ctx, query, status, errMsg, id)
@@ -246,17 +363,171 @@ func (s *RepositoryStore) UpdateSyncStatus(ctx context.Context, id int, status s
 	return nil
 }
 
+// RefreshSyncHeartbeat extends the lease of an in-progress sync. It reports whether the sync
+// should keep going: false if cancellation was requested, or if the repository is no longer
+// syncing (deleted, or its sync recovered as stale).
+func (s *RepositoryStore) RefreshSyncHeartbeat(ctx context.Context, id int) (bool, error) {
+	var cancelRequested bool
+	err := s.db.QueryRow(ctx, `
+		UPDATE repositories
+		SET sync_heartbeat_at = NOW()
+		WHERE id = $1 AND last_sync_status = 'syncing'
+		RETURNING sync_cancel_requested
+	`, id).Scan(&cancelRequested)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return false, nil
+		}
+		log.Printf("Error refreshing sync heartbeat for repo ID %d: %v", id, err)
+		return false, fmt.Errorf("failed to refresh sync heartbeat: %w", err)
+	}
+	return !cancelRequested, nil
+}
+
+// RequestSyncCancel asks the instance running a repository's sync to cancel it; the request is
+// picked up with the sync's next heartbeat. It reports whether the repository is syncing.
+func (s *RepositoryStore) RequestSyncCancel(ctx context.Context, id int) (bool, error) {
+	var syncing bool
+	err := s.db.QueryRow(ctx, `
+		UPDATE repositories
+		SET sync_cancel_requested = (last_sync_status = 'syncing')
+		WHERE id = $1
+		RETURNING last_sync_status = 'syncing'
+	`, id).Scan(&syncing)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return false, fmt.Errorf("repository with ID %d not found", id)
+		}
+		log.Printf("Error requesting sync cancellation for repo ID %d: %v", id, err)
+		return false, fmt.Errorf("failed to request sync cancellation: %w", err)
+	}
+	return syncing, nil
+}
+
+// RecoverStaleSyncs marks syncs whose heartbeat is older than lease as failed, along with
+// their running sync runs and jobs, and returns the IDs of the affected repositories.
+// Running jobs older than lease whose repository is not syncing are failed as well,
+// since their worker died before the sync started.
+func (s *RepositoryStore) RecoverStaleSyncs(ctx context.Context, lease time.Duration) ([]int, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx) // No-op if the transaction was committed
+
+	const staleError = "sync interrupted: the process running it stopped responding"
+	rows, err := tx.Query(ctx, `
+		UPDATE repositories
+		SET last_sync_status = 'failed', last_sync_error = $1, sync_heartbeat_at = NULL, updated_at = NOW()
+		WHERE last_sync_status = 'syncing'
+		  AND (sync_heartbeat_at IS NULL OR sync_heartbeat_at < NOW() - make_interval(secs => $2::float8))
+		RETURNING id
+	`, staleError, lease.Seconds())
+	if err != nil {
+		log.Printf("Error recovering stale syncs: %v", err)
+		return nil, fmt.Errorf("failed to recover stale syncs: %w", err)
+	}
+	ids, err := pgx.CollectRows(rows, pgx.RowTo[int])
+	if err != nil {
+		log.Printf("Error reading recovered repositories: %v", err)
+		return nil, fmt.Errorf("failed to read recovered repositories: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx, `
+		UPDATE sync_runs
+		SET status = 'failed', finished_at = NOW(), error = $1
+		WHERE status = 'running' AND repository_id = ANY($2)
+	`, staleError, ids); err != nil {
+		log.Printf("Error failing stale sync runs: %v", err)
+		return nil, fmt.Errorf("failed to fail stale sync runs: %w", err)
+	}
+
+	if _, err := tx.Exec(ctx, `
+		UPDATE sync_jobs
+		SET status = 'failed', last_error = $1, finished_at = NOW()
+		WHERE status = 'running'
+		  AND (repository_id = ANY($2)
+		       OR (started_at < NOW() - make_interval(secs => $3::float8)
+		           AND repository_id NOT IN (SELECT id FROM repositories WHERE last_sync_status = 'syncing')))
+	`, staleError, ids, lease.Seconds()); err != nil {
+		log.Printf("Error failing stale sync jobs: %v", err)
+		return nil, fmt.Errorf("failed to fail stale sync jobs: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		log.Printf("Error committing stale sync recovery: %v", err)
+		return nil, fmt.Errorf("failed to commit stale sync recovery: %w", err)
+	}
+	return ids, nil
+}
+
 // UpdateSyncSuccess updates the repository content and marks the sync as successful.
-func (s *RepositoryStore) UpdateSyncSuccess(ctx context.Context, id int, content string) error {
+// files are the synced files, in aggregation order, and replace the per-file cache in the
+// same transaction, so the cache always matches the stored content.
+// skipped lists the files that were left out of the content and why; it may be empty.
+// commitSHA and treeSHA identify the synced upstream state for change detection on the next sync.
+func (s *RepositoryStore) UpdateSyncSuccess(ctx context.Context, id int, content string, files []RepositoryFile, skipped []SkippedFile, ref, commitSHA, treeSHA string) error {
+	return s.updateSyncContent(ctx, id, "success", content, files, skipped, nil, ref, commitSHA, treeSHA)
+}
+
+// UpdateSyncPartial stores the content and files of a sync in which some files failed to
+// fetch and marks it as partial. skipped lists the failed (and otherwise skipped) files.
+// The commit the content was synced from is recorded, but not the tree SHA, so the next
+// sync retries in full.
+func (s *RepositoryStore) UpdateSyncPartial(ctx context.Context, id int, content string, files []RepositoryFile, skipped []SkippedFile, ref, commitSHA string, syncError error) error {
+	return s.updateSyncContent(ctx, id, "partial", content, files, skipped, syncError, ref, commitSHA, "")
+}
+
+// updateSyncContent stores the aggregated content, files and outcome of a completed sync,
+// along with the ref and commit it was synced from.
+func (s *RepositoryStore) updateSyncContent(ctx context.Context, id int, status, content string, files []RepositoryFile, skipped []SkippedFile, syncError error, ref, commitSHA, treeSHA string) error {
+	if skipped == nil {
+		skipped = []SkippedFile{} // Store an empty JSON array rather than NULL
+	}
+	var errMsg sql.NullString
+	if syncError != nil {
+		errMsg = sql.NullString{String: syncError.Error(), Valid: true}
+	}
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx) // No-op if the transaction was committed
+
+	query := `
+		UPDATE repositories
+		SET aggregated_content = $1, last_sync_status = $2, last_sync_time = $3, last_sync_error = $4, skipped_files = $5,
+		    resolved_ref = NULLIF($6, ''), last_commit_sha = NULLIF($7, ''), docs_tree_sha = NULLIF($8, ''), updated_at = NOW()
+		WHERE id = $9
+	`
+	_, err = tx.Exec(ctx, query, content, status, time.Now(), errMsg, skipped, ref, commitSHA, treeSHA, id)
+	if err != nil {
+		log.Printf("Error updating sync %s for repo ID %d: %v", status, id, err)
+		return fmt.Errorf("failed to update sync %s data: %w", status, err)
+	}
+	if err := replaceRepositoryFiles(ctx, tx, id, files); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit sync %s data: %w", status, err)
+	}
+	return nil
+}
+
+// UpdateSyncUnchanged marks a sync as successful without touching the stored content,
+// used when the upstream docs have not changed since the last successful sync.
+func (s *RepositoryStore) UpdateSyncUnchanged(ctx context.Context, id int, ref, commitSHA string) error {
 	query := `
 		UPDATE repositories
-		SET aggregated_content = $1, last_sync_status = $2, last_sync_time = $3, last_sync_error = NULL, updated_at = NOW()
+		SET last_sync_status = 'success', last_sync_time = $1, last_sync_error = NULL, resolved_ref = NULLIF($2, ''), last_commit_sha = $3, updated_at = NOW()
 		WHERE id = $4
 	`
-	_, err := s.db.Exec(ctx, query, content, "success", time.Now(), id)
+	_, err := s.db.Exec(ctx, query, time.Now(), ref, commitSHA, id)
 	if err != nil {
-		log.Printf("Error updating sync success for repo ID %d: %v", id, err)
-		return fmt.Errorf("failed to update sync success data: %w", err)
+		log.Printf("Error updating unchanged sync for repo ID %d: %v", id, err)
+		return fmt.Errorf("failed to update unchanged sync: %w", err)
 	}
 	return nil
 }
@@ -264,7 +535,7 @@ func (s *RepositoryStore) UpdateSyncSuccess(ctx context.Context, id int, content
 // GetAllRepositoriesForSync retrieves all repositories to be processed by the syncer.
 func (s *RepositoryStore) GetAllRepositoriesForSync(ctx context.Context) ([]Repository, error) {
 	query := `
-		SELECT id, url, owner, repo_name, docs_path, extensions, branch
+		SELECT id, url, owner, repo_name, docs_path, extensions, branch, ref_mode, COALESCE(schedule, '')
 		FROM repositories
 		ORDER BY id ASC -- Or any other order suitable for processing
 	`
@@ -286,6 +557,8 @@ func (s *RepositoryStore) GetAllRepositoriesForSync(ctx context.Context) ([]Repo
 			&repo.DocsPath,
 			&repo.Extensions,
 			&repo.Branch,
+			&repo.RefMode,
+			&repo.Schedule,
 		)
 		if err != nil {
 			log.Printf("Error scanning repository row for sync: %v", err)
@@ -301,3 +574,37 @@ func (s *RepositoryStore) GetAllRepositoriesForSync(ctx context.Context) ([]Repo
 
 	return repos, nil
 }
+
+// FindRepositoriesByGitHubRepo retrieves the repositories tracking a given GitHub owner/repo,
+// matched case-insensitively. Only the fields needed to route webhook events are populated.
+func (s *RepositoryStore) FindRepositoriesByGitHubRepo(ctx context.Context, owner, repoName string) ([]Repository, error) {
+	query := `
+		SELECT id, url, owner, repo_name, docs_path, source_paths, COALESCE(branch, ''), ref_mode, COALESCE(ref_pattern, '')
+		FROM repositories
+		WHERE LOWER(owner) = LOWER($1) AND LOWER(repo_name) = LOWER($2)
+		ORDER BY id ASC
+	`
+	rows, err := s.db.Query(ctx, query, owner, repoName)
+	if err != nil {
+		log.Printf("Error finding repositories for %s/%s: %v", owner, repoName, err)
+		return nil, fmt.Errorf("failed to find repositories: %w", err)
+	}
+	defer rows.Close()
+
+	var repos []Repository
+	for rows.Next() {
+		var repo Repository
+		if err := rows.Scan(&repo.ID, &repo.URL, &repo.Owner, &repo.RepoName, &repo.DocsPath, &repo.SourcePaths, &repo.Branch, &repo.RefMode, &repo.RefPattern); err != nil {
+			log.Printf("Error scanning repository row for %s/%s: %v", owner, repoName, err)
+			continue // Skip problematic row
+		}
+		repos = append(repos, repo)
+	}
+
+	if err = rows.Err(); err != nil {
+		log.Printf("Error after iterating repository rows for %s/%s: %v", owner, repoName, err)
+		return nil, fmt.Errorf("failed during repository iteration: %w", err)
+	}
+
+	return repos, nil
+}
@@ -0,0 +1,214 @@
+package database
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// --- Methods for content snapshots ---
+
+// snapshotColumns lists the metadata columns scanned into a Snapshot by scanSnapshot, in order.
+const snapshotColumns = `id, repository_id, content_hash, COALESCE(commit_sha, ''), file_count, size, created_at`
+
+// scanSnapshot scans a row selected with snapshotColumns into snapshot.
+func scanSnapshot(row pgx.Row, snapshot *Snapshot, extra ...any) error {
+	dest := []any{
+		&snapshot.ID,
+		&snapshot.RepositoryID,
+		&snapshot.ContentHash,
+		&snapshot.CommitSHA,
+		&snapshot.FileCount,
+		&snapshot.Size,
+		&snapshot.CreatedAt,
+	}
+	return row.Scan(append(dest, extra...)...)
+}
+
+// CreateSnapshot stores the aggregated content and files of a sync as a new snapshot.
+// If the content is identical to the repository's latest snapshot, no snapshot is created
+// and the latest one is returned with created set to false.
+func (s *RepositoryStore) CreateSnapshot(ctx context.Context, repoID int, commitSHA, content string, files []RepositoryFile) (snapshot *Snapshot, created bool, err error) {
+	sum := sha256.Sum256([]byte(content))
+	contentHash := hex.EncodeToString(sum[:])
+
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx) // No-op if the transaction was committed
+
+	// Deduplicate against the latest snapshot
+	var latest Snapshot
+	err = scanSnapshot(tx.QueryRow(ctx, `
+		SELECT `+snapshotColumns+`
+		FROM snapshots
+		WHERE repository_id = $1
+		ORDER BY created_at DESC, id DESC
+		LIMIT 1
+	`, repoID), &latest)
+	if err == nil && latest.ContentHash == contentHash {
+		return &latest, false, nil
+	}
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		log.Printf("Error getting latest snapshot for repo ID %d: %v", repoID, err)
+		return nil, false, fmt.Errorf("failed to get latest snapshot: %w", err)
+	}
+
+	snapshot = &Snapshot{}
+	err = scanSnapshot(tx.QueryRow(ctx, `
+		INSERT INTO snapshots (repository_id, content_hash, commit_sha, file_count, size, content)
+		VALUES ($1, $2, NULLIF($3, ''), $4, $5, $6)
+		RETURNING `+snapshotColumns,
+		repoID, contentHash, commitSHA, len(files), len(content), content,
+	), snapshot)
+	if err != nil {
+		log.Printf("Error creating snapshot for repo ID %d: %v", repoID, err)
+		return nil, false, fmt.Errorf("failed to create snapshot: %w", err)
+	}
+
+	batch := &pgx.Batch{}
+	for _, file := range files {
+		batch.Queue(`INSERT INTO snapshot_files (snapshot_id, path, sha, content) VALUES ($1, $2, $3, $4)`,
+			snapshot.ID, file.Path, file.SHA, file.Content)
+	}
+	if err := tx.SendBatch(ctx, batch).Close(); err != nil {
+		log.Printf("Error storing snapshot files for snapshot %d: %v", snapshot.ID, err)
+		return nil, false, fmt.Errorf("failed to store snapshot files: %w", err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, false, fmt.Errorf("failed to commit snapshot: %w", err)
+	}
+	snapshot.Content = content
+	return snapshot, true, nil
+}
+
+// ListSnapshots retrieves a page of snapshots (without content) for a repository, newest first,
+// along with the total number of snapshots stored for it.
+func (s *RepositoryStore) ListSnapshots(ctx context.Context, repoID, limit, offset int) ([]Snapshot, int, error) {
+	var total int
+	err := s.db.QueryRow(ctx, `SELECT COUNT(*) FROM snapshots WHERE repository_id = $1`, repoID).Scan(&total)
+	if err != nil {
+		log.Printf("Error counting snapshots for repo ID %d: %v", repoID, err)
+		return nil, 0, fmt.Errorf("failed to count snapshots: %w", err)
+	}
+
+	query := `
+		SELECT ` + snapshotColumns + `
+		FROM snapshots
+		WHERE repository_id = $1
+		ORDER BY created_at DESC, id DESC
+		LIMIT $2 OFFSET $3
+	`
+	rows, err := s.db.Query(ctx, query, repoID, limit, offset)
+	if err != nil {
+		log.Printf("Error listing snapshots for repo ID %d: %v", repoID, err)
+		return nil, 0, fmt.Errorf("failed to list snapshots: %w", err)
+	}
+	defer rows.Close()
+
+	snapshots := []Snapshot{}
+	for rows.Next() {
+		var snapshot Snapshot
+		if err := scanSnapshot(rows, &snapshot); err != nil {
+			log.Printf("Error scanning snapshot row: %v", err)
+			continue // Skip problematic row
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+
+	if err = rows.Err(); err != nil {
+		log.Printf("Error after iterating snapshot rows: %v", err)
+		return nil, 0, fmt.Errorf("failed during snapshot iteration: %w", err)
+	}
+
+	return snapshots, total, nil
+}
+
+// GetSnapshot retrieves a single snapshot of a repository, including its content.
+func (s *RepositoryStore) GetSnapshot(ctx context.Context, repoID, snapshotID int) (*Snapshot, error) {
+	query := `
+		SELECT ` + snapshotColumns + `, content
+		FROM snapshots
+		WHERE repository_id = $1 AND id = $2
+	`
+	var snapshot Snapshot
+	err := scanSnapshot(s.db.QueryRow(ctx, query, repoID, snapshotID), &snapshot, &snapshot.Content)
+	if err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil, fmt.Errorf("snapshot %d not found for repository %d", snapshotID, repoID)
+		}
+		log.Printf("Error getting snapshot %d for repo ID %d: %v", snapshotID, repoID, err)
+		return nil, fmt.Errorf("failed to get snapshot: %w", err)
+	}
+	return &snapshot, nil
+}
+
+// PruneSnapshots deletes snapshots of a repository that fall outside the retention policy:
+// those beyond the keepLast most recent (if keepLast > 0) or older than maxAge (if maxAge > 0).
+// The most recent snapshot is always kept. It returns the number of snapshots deleted.
+func (s *RepositoryStore) PruneSnapshots(ctx context.Context, repoID, keepLast int, maxAge time.Duration) (int64, error) {
+	if keepLast <= 0 && maxAge <= 0 {
+		return 0, nil // Retention disabled
+	}
+
+	query := `
+		DELETE FROM snapshots
+		WHERE id IN (
+			SELECT id FROM (
+				SELECT id, created_at, ROW_NUMBER() OVER (ORDER BY created_at DESC, id DESC) AS rank
+				FROM snapshots
+				WHERE repository_id = $1
+			) ranked
+			WHERE rank > 1
+			  AND (($2::int > 0 AND rank > $2::int) OR ($3::float8 > 0 AND created_at < NOW() - make_interval(secs => $3::float8)))
+		)
+	`
+	commandTag, err := s.db.Exec(ctx, query, repoID, keepLast, maxAge.Seconds())
+	if err != nil {
+		log.Printf("Error pruning snapshots for repo ID %d: %v", repoID, err)
+		return 0, fmt.Errorf("failed to prune snapshots: %w", err)
+	}
+	return commandTag.RowsAffected(), nil
+}
+
+// GetSnapshotFiles retrieves the files contained in a snapshot of a repository, ordered by path.
+func (s *RepositoryStore) GetSnapshotFiles(ctx context.Context, repoID, snapshotID int) ([]RepositoryFile, error) {
+	query := `
+		SELECT sf.path, sf.sha, sf.content
+		FROM snapshot_files sf
+		JOIN snapshots sn ON sn.id = sf.snapshot_id
+		WHERE sn.repository_id = $1 AND sf.snapshot_id = $2
+		ORDER BY sf.path
+	`
+	rows, err := s.db.Query(ctx, query, repoID, snapshotID)
+	if err != nil {
+		log.Printf("Error getting files of snapshot %d for repo ID %d: %v", snapshotID, repoID, err)
+		return nil, fmt.Errorf("failed to get snapshot files: %w", err)
+	}
+	defer rows.Close()
+
+	var files []RepositoryFile
+	for rows.Next() {
+		file := RepositoryFile{RepositoryID: repoID}
+		if err := rows.Scan(&file.Path, &file.SHA, &file.Content); err != nil {
+			log.Printf("Error scanning snapshot file row: %v", err)
+			return nil, fmt.Errorf("failed to read snapshot file: %w", err) // A missing file would corrupt the diff
+		}
+		files = append(files, file)
+	}
+
+	if err = rows.Err(); err != nil {
+		log.Printf("Error after iterating snapshot file rows: %v", err)
+		return nil, fmt.Errorf("failed during snapshot file iteration: %w", err)
+	}
+
+	return files, nil
+}
@@ -0,0 +1,129 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// --- Methods for the sync run history ---
+
+// syncRunColumns lists the columns scanned into a SyncRun by scanSyncRun, in order.
+const syncRunColumns = `id, repository_id, triggered_by, status, started_at, finished_at, COALESCE(ref, ''), COALESCE(commit_sha, ''), files_listed, files_fetched, files_reused, files_skipped, files_ignored, ignore_rules, bytes_fetched, api_calls, COALESCE(error, '')`
+
+// scanSyncRun scans a row selected with syncRunColumns into run.
+func scanSyncRun(row pgx.Row, run *SyncRun) error {
+	return row.Scan(
+		&run.ID,
+		&run.RepositoryID,
+		&run.Trigger,
+		&run.Status,
+		&run.StartedAt,
+		&run.FinishedAt,
+		&run.Ref,
+		&run.CommitSHA,
+		&run.FilesListed,
+		&run.FilesFetched,
+		&run.FilesReused,
+		&run.FilesSkipped,
+		&run.FilesIgnored,
+		&run.IgnoreRules,
+		&run.BytesFetched,
+		&run.APICalls,
+		&run.Error,
+	)
+}
+
+// CreateSyncRun records the start of a sync run for a repository.
+func (s *RepositoryStore) CreateSyncRun(ctx context.Context, repoID int, trigger string) (*SyncRun, error) {
+	query := `
+		INSERT INTO sync_runs (repository_id, triggered_by, status)
+		VALUES ($1, $2, 'running')
+		RETURNING ` + syncRunColumns
+	var run SyncRun
+	if err := scanSyncRun(s.db.QueryRow(ctx, query, repoID, trigger), &run); err != nil {
+		log.Printf("Error creating sync run for repo ID %d: %v", repoID, err)
+		return nil, fmt.Errorf("failed to create sync run: %w", err)
+	}
+	return &run, nil
+}
+
+// FinishSyncRun records the outcome and statistics of a sync run.
+func (s *RepositoryStore) FinishSyncRun(ctx context.Context, run *SyncRun) error {
+	ignoreRules := run.IgnoreRules
+	if ignoreRules == nil {
+		ignoreRules = []string{} // Store an empty JSON array rather than NULL
+	}
+	query := `
+		UPDATE sync_runs
+		SET status = $1, finished_at = NOW(), ref = NULLIF($2, ''), commit_sha = NULLIF($3, ''), files_listed = $4, files_fetched = $5,
+		    files_reused = $6, files_skipped = $7, files_ignored = $8, ignore_rules = $9, bytes_fetched = $10, api_calls = $11,
+		    error = NULLIF($12, '')
+		WHERE id = $13
+		RETURNING finished_at
+	`
+	err := s.db.QueryRow(ctx, query,
+		run.Status,
+		run.Ref,
+		run.CommitSHA,
+		run.FilesListed,
+		run.FilesFetched,
+		run.FilesReused,
+		run.FilesSkipped,
+		run.FilesIgnored,
+		ignoreRules,
+		run.BytesFetched,
+		run.APICalls,
+		run.Error,
+		run.ID,
+	).Scan(&run.FinishedAt)
+	if err != nil {
+		log.Printf("Error finishing sync run %d: %v", run.ID, err)
+		return fmt.Errorf("failed to finish sync run: %w", err)
+	}
+	return nil
+}
+
+// ListSyncRuns retrieves a page of sync runs for a repository, newest first,
+// along with the total number of runs recorded for it.
+func (s *RepositoryStore) ListSyncRuns(ctx context.Context, repoID, limit, offset int) ([]SyncRun, int, error) {
+	var total int
+	err := s.db.QueryRow(ctx, `SELECT COUNT(*) FROM sync_runs WHERE repository_id = $1`, repoID).Scan(&total)
+	if err != nil {
+		log.Printf("Error counting sync runs for repo ID %d: %v", repoID, err)
+		return nil, 0, fmt.Errorf("failed to count sync runs: %w", err)
+	}
+
+	query := `
+		SELECT ` + syncRunColumns + `
+		FROM sync_runs
+		WHERE repository_id = $1
+		ORDER BY started_at DESC, id DESC
+		LIMIT $2 OFFSET $3
+	`
+	rows, err := s.db.Query(ctx, query, repoID, limit, offset)
+	if err != nil {
+		log.Printf("Error listing sync runs for repo ID %d: %v", repoID, err)
+		return nil, 0, fmt.Errorf("failed to list sync runs: %w", err)
+	}
+	defer rows.Close()
+
+	runs := []SyncRun{}
+	for rows.Next() {
+		var run SyncRun
+		if err := scanSyncRun(rows, &run); err != nil {
+			log.Printf("Error scanning sync run row: %v", err)
+			continue // Skip problematic row
+		}
+		runs = append(runs, run)
+	}
+
+	if err = rows.Err(); err != nil {
+		log.Printf("Error after iterating sync run rows: %v", err)
+		return nil, 0, fmt.Errorf("failed during sync run iteration: %w", err)
+	}
+
+	return runs, total, nil
+}
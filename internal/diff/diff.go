@@ -0,0 +1,207 @@
+package diff
+
+import (
+	"fmt"
+	"strings"
+)
+
+// opKind identifies a line-level edit operation.
+type opKind int
+
+const (
+	opEqual opKind = iota
+	opDelete
+	opInsert
+)
+
+// op is a single line-level edit. aLine and bLine are 0-based line indexes into
+// the old and new text; only the one relevant to the kind is meaningful.
+type op struct {
+	kind  opKind
+	aLine int
+	bLine int
+}
+
+// Unified returns a unified diff between a and b with the given number of context lines.
+// fromName and toName are used in the "---" and "+++" headers. An empty string is
+// returned if the texts are identical.
+func Unified(fromName, toName, a, b string, context int) string {
+	if a == b {
+		return ""
+	}
+
+	aLines := splitLines(a)
+	bLines := splitLines(b)
+	ops := myers(aLines, bLines)
+
+	var out strings.Builder
+	fmt.Fprintf(&out, "--- %s\n", fromName)
+	fmt.Fprintf(&out, "+++ %s\n", toName)
+
+	for _, h := range hunks(ops, context) {
+		writeHunk(&out, ops[h[0]:h[1]], aLines, bLines)
+	}
+	return out.String()
+}
+
+// splitLines splits text into lines without their trailing newlines.
+func splitLines(text string) []string {
+	if text == "" {
+		return nil
+	}
+	lines := strings.Split(text, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1] // Trailing newline does not start a new line
+	}
+	return lines
+}
+
+// myers computes a shortest edit script from a to b using Myers' O(ND) algorithm.
+func myers(a, b []string) []op {
+	n, m := len(a), len(b)
+	max := n + m
+	offset := max + 1
+	v := make([]int, 2*max+3)
+	var trace [][]int
+
+	// Forward pass: record the furthest reaching x for each diagonal k at every step d
+search:
+	for d := 0; d <= max; d++ {
+		snapshot := make([]int, len(v))
+		copy(snapshot, v)
+		trace = append(trace, snapshot)
+
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1] // Move down (insertion)
+			} else {
+				x = v[offset+k-1] + 1 // Move right (deletion)
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			v[offset+k] = x
+			if x >= n && y >= m {
+				break search
+			}
+		}
+	}
+
+	// Backtrack through the recorded steps to build the edit script in reverse
+	var ops []op
+	x, y := n, m
+	for d := len(trace) - 1; d >= 0; d-- {
+		vd := trace[d]
+		k := x - y
+		var prevK int
+		if k == -d || (k != d && vd[offset+k-1] < vd[offset+k+1]) {
+			prevK = k + 1
+		} else {
+			prevK = k - 1
+		}
+		prevX := vd[offset+prevK]
+		prevY := prevX - prevK
+
+		for x > prevX && y > prevY {
+			x--
+			y--
+			ops = append(ops, op{kind: opEqual, aLine: x, bLine: y})
+		}
+		if d > 0 {
+			if x == prevX {
+				y--
+				ops = append(ops, op{kind: opInsert, aLine: x, bLine: y})
+			} else {
+				x--
+				ops = append(ops, op{kind: opDelete, aLine: x, bLine: y})
+			}
+		}
+	}
+
+	for i, j := 0, len(ops)-1; i < j; i, j = i+1, j-1 {
+		ops[i], ops[j] = ops[j], ops[i]
+	}
+	return ops
+}
+
+// hunks groups the changed operations with up to context surrounding equal lines.
+// Each hunk is returned as a [start, end) range into ops.
+func hunks(ops []op, context int) [][2]int {
+	var result [][2]int
+	for i := 0; i < len(ops); i++ {
+		if ops[i].kind == opEqual {
+			continue
+		}
+		start := i - context
+		if start < 0 {
+			start = 0
+		}
+		// Extend the hunk while the gap between changes is small enough to merge
+		end := i
+		for end < len(ops) {
+			if ops[end].kind != opEqual {
+				end++
+				continue
+			}
+			gap := end
+			for gap < len(ops) && ops[gap].kind == opEqual {
+				gap++
+			}
+			if gap == len(ops) || gap-end > 2*context {
+				end += min(context, gap-end)
+				break
+			}
+			end = gap
+		}
+		if len(result) > 0 && start <= result[len(result)-1][1] {
+			result[len(result)-1][1] = end
+		} else {
+			result = append(result, [2]int{start, end})
+		}
+		i = end - 1
+	}
+	return result
+}
+
+// writeHunk writes a single hunk with its "@@" header.
+func writeHunk(out *strings.Builder, ops []op, aLines, bLines []string) {
+	aStart, bStart := ops[0].aLine, ops[0].bLine
+	aCount, bCount := 0, 0
+	for _, o := range ops {
+		switch o.kind {
+		case opEqual:
+			aCount++
+			bCount++
+		case opDelete:
+			aCount++
+		case opInsert:
+			bCount++
+		}
+	}
+
+	fmt.Fprintf(out, "@@ -%s +%s @@\n", hunkRange(aStart, aCount), hunkRange(bStart, bCount))
+	for _, o := range ops {
+		switch o.kind {
+		case opEqual:
+			out.WriteString(" " + aLines[o.aLine] + "\n")
+		case opDelete:
+			out.WriteString("-" + aLines[o.aLine] + "\n")
+		case opInsert:
+			out.WriteString("+" + bLines[o.bLine] + "\n")
+		}
+	}
+}
+
+// hunkRange formats a 0-based start and line count as a unified diff range.
+func hunkRange(start, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", start) // An empty range refers to the line before it
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", start+1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, count)
+}
@@ -0,0 +1,263 @@
+package diff
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestUnified(t *testing.T) {
+	tests := []struct {
+		name    string
+		a, b    string
+		context int
+		want    string
+	}{
+		{
+			name: "identical",
+			a:    "a\nb\n",
+			b:    "a\nb\n",
+			want: "",
+		},
+		{
+			name:    "changed line",
+			a:       "a\nb\nc\n",
+			b:       "a\nB\nc\n",
+			context: 1,
+			want:    "--- old\n+++ new\n@@ -1,3 +1,3 @@\n a\n-b\n+B\n c\n",
+		},
+		{
+			name:    "insertion into empty text",
+			a:       "",
+			b:       "a\nb\n",
+			context: 3,
+			want:    "--- old\n+++ new\n@@ -0,0 +1,2 @@\n+a\n+b\n",
+		},
+		{
+			name:    "deletion of every line",
+			a:       "a\nb\n",
+			b:       "",
+			context: 3,
+			want:    "--- old\n+++ new\n@@ -1,2 +0,0 @@\n-a\n-b\n",
+		},
+		{
+			name:    "insertion without context refers to the line before",
+			a:       "a\nb\nc\n",
+			b:       "a\nb\nx\nc\n",
+			context: 0,
+			want:    "--- old\n+++ new\n@@ -2,0 +3 @@\n+x\n",
+		},
+		{
+			name:    "context clipped at the start and end",
+			a:       "a\nb\n",
+			b:       "x\nb\n",
+			context: 3,
+			want:    "--- old\n+++ new\n@@ -1,2 +1,2 @@\n-a\n+x\n b\n",
+		},
+		{
+			name:    "nearby changes share a hunk",
+			a:       "1\n2\n3\n4\n5\n6\n7\n",
+			b:       "1\nX\n3\n4\n5\nY\n7\n",
+			context: 2,
+			want:    "--- old\n+++ new\n@@ -1,7 +1,7 @@\n 1\n-2\n+X\n 3\n 4\n 5\n-6\n+Y\n 7\n",
+		},
+		{
+			name:    "distant changes get separate hunks",
+			a:       "1\n2\n3\n4\n5\n6\n7\n8\n9\n",
+			b:       "X\n2\n3\n4\n5\n6\n7\n8\nY\n",
+			context: 1,
+			want:    "--- old\n+++ new\n@@ -1,2 +1,2 @@\n-1\n+X\n 2\n@@ -8,2 +8,2 @@\n 8\n-9\n+Y\n",
+		},
+		{
+			name:    "gap of exactly twice the context is merged",
+			a:       "1\n2\n3\n4\n5\n6\n",
+			b:       "X\n2\n3\n4\n5\nY\n",
+			context: 2,
+			want:    "--- old\n+++ new\n@@ -1,6 +1,6 @@\n-1\n+X\n 2\n 3\n 4\n 5\n-6\n+Y\n",
+		},
+		{
+			name:    "missing trailing newline",
+			a:       "a\nb",
+			b:       "a\nc",
+			context: 1,
+			want:    "--- old\n+++ new\n@@ -1,2 +1,2 @@\n a\n-b\n+c\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Unified("old", "new", tt.a, tt.b, tt.context); got != tt.want {
+				t.Errorf("Unified() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestMyersMinimal checks on random texts that the edit script turns a into b and is as
+// short as possible.
+func TestMyersMinimal(t *testing.T) {
+	rng := rand.New(rand.NewPCG(1, 2))
+	randomLines := func() []string {
+		lines := make([]string, rng.IntN(12))
+		for i := range lines {
+			lines[i] = strconv.Itoa(rng.IntN(4)) // A small alphabet makes many equal lines
+		}
+		return lines
+	}
+	for i := 0; i < 500; i++ {
+		a, b := randomLines(), randomLines()
+		ops := myers(a, b)
+
+		var gotA, gotB []string
+		edits := 0
+		for _, o := range ops {
+			switch o.kind {
+			case opEqual:
+				if a[o.aLine] != b[o.bLine] {
+					t.Fatalf("myers(%q, %q): equal op pairs %q with %q", a, b, a[o.aLine], b[o.bLine])
+				}
+				gotA = append(gotA, a[o.aLine])
+				gotB = append(gotB, b[o.bLine])
+			case opDelete:
+				gotA = append(gotA, a[o.aLine])
+				edits++
+			case opInsert:
+				gotB = append(gotB, b[o.bLine])
+				edits++
+			}
+		}
+		if strings.Join(gotA, ",") != strings.Join(a, ",") || strings.Join(gotB, ",") != strings.Join(b, ",") {
+			t.Fatalf("myers(%q, %q) does not reproduce the inputs: %q, %q", a, b, gotA, gotB)
+		}
+		if want := len(a) + len(b) - 2*lcsLength(a, b); edits != want {
+			t.Fatalf("myers(%q, %q) uses %d edits, want %d", a, b, edits, want)
+		}
+	}
+}
+
+// TestUnifiedApplies checks on random texts that applying the diff to a yields b.
+func TestUnifiedApplies(t *testing.T) {
+	rng := rand.New(rand.NewPCG(3, 4))
+	randomText := func() string {
+		var text strings.Builder
+		for n := rng.IntN(20); n > 0; n-- {
+			fmt.Fprintf(&text, "%d\n", rng.IntN(5))
+		}
+		return text.String()
+	}
+	for i := 0; i < 500; i++ {
+		a, b := randomText(), randomText()
+		context := rng.IntN(4)
+		patch := Unified("old", "new", a, b, context)
+		got, err := apply(a, patch)
+		if err != nil {
+			t.Fatalf("applying diff of %q and %q (context %d): %v\n%s", a, b, context, err, patch)
+		}
+		if got != b {
+			t.Fatalf("applying diff of %q and %q (context %d) gave %q\n%s", a, b, context, got, patch)
+		}
+	}
+}
+
+// lcsLength returns the length of the longest common subsequence of a and b.
+func lcsLength(a, b []string) int {
+	prev := make([]int, len(b)+1)
+	for i := range a {
+		cur := make([]int, len(b)+1)
+		for j := range b {
+			if a[i] == b[j] {
+				cur[j+1] = prev[j] + 1
+			} else {
+				cur[j+1] = max(prev[j+1], cur[j])
+			}
+		}
+		prev = cur
+	}
+	return prev[len(b)]
+}
+
+// apply applies a unified diff produced by Unified to text, checking the hunk headers and
+// context lines along the way.
+func apply(text, patch string) (string, error) {
+	if patch == "" {
+		return text, nil
+	}
+	a := splitLines(text)
+	lines := strings.Split(strings.TrimSuffix(patch, "\n"), "\n")[2:] // Skip the file headers
+	var out []string
+	next := 0 // Index of the next line of a to copy
+	for i := 0; i < len(lines); {
+		var aStart, aCount, bStart, bCount int
+		if err := parseHunkHeader(lines[i], &aStart, &aCount, &bStart, &bCount); err != nil {
+			return "", err
+		}
+		i++
+		// A start refers to the line before an empty range
+		from := aStart - 1
+		if aCount == 0 {
+			from = aStart
+		}
+		if from < next {
+			return "", fmt.Errorf("hunk %q overlaps the previous one", lines[i-1])
+		}
+		out = append(out, a[next:from]...)
+		next = from
+
+		seenA, seenB := 0, 0
+		for ; i < len(lines) && !strings.HasPrefix(lines[i], "@@"); i++ {
+			line := lines[i]
+			switch line[0] {
+			case ' ', '-':
+				if next >= len(a) || a[next] != line[1:] {
+					return "", fmt.Errorf("line %q does not match the text", line)
+				}
+				next++
+				seenA++
+				if line[0] == ' ' {
+					out = append(out, line[1:])
+					seenB++
+				}
+			case '+':
+				out = append(out, line[1:])
+				seenB++
+			default:
+				return "", fmt.Errorf("unexpected line %q", line)
+			}
+		}
+		if seenA != aCount || seenB != bCount {
+			return "", fmt.Errorf("hunk counts %d,%d do not match its lines %d,%d", aCount, bCount, seenA, seenB)
+		}
+	}
+	out = append(out, a[next:]...)
+	if len(out) == 0 {
+		return "", nil
+	}
+	return strings.Join(out, "\n") + "\n", nil
+}
+
+// parseHunkHeader reads the ranges of a "@@ -a,n +b,m @@" line.
+func parseHunkHeader(line string, aStart, aCount, bStart, bCount *int) error {
+	fields := strings.Fields(line)
+	if len(fields) != 4 || fields[0] != "@@" || fields[3] != "@@" {
+		return fmt.Errorf("malformed hunk header %q", line)
+	}
+	parseRange := func(r string, start, count *int) error {
+		startText, countText, found := strings.Cut(r[1:], ",")
+		var err error
+		if *start, err = strconv.Atoi(startText); err != nil {
+			return fmt.Errorf("malformed hunk header %q", line)
+		}
+		*count = 1
+		if found {
+			if *count, err = strconv.Atoi(countText); err != nil {
+				return fmt.Errorf("malformed hunk header %q", line)
+			}
+		}
+		return nil
+	}
+	if err := parseRange(fields[1], aStart, aCount); err != nil {
+		return err
+	}
+	return parseRange(fields[2], bStart, bCount)
+}
@@ -0,0 +1,119 @@
+package events
+
+import (
+	"log"
+	"sync"
+	"time"
+)
+
+// Event types published while a repository syncs.
+const (
+	TypeSyncStarted = "sync_started"   // The sync began
+	TypeListing     = "listing"        // Listing the files of the docs path
+	TypeFilesFound  = "files_found"    // Total holds the number of files to sync
+	TypeFileFetched = "file_fetched"   // Current of Total files processed; Path is the file
+	TypeSyncDone    = "sync_done"      // Status holds the outcome: success, partial, unchanged
+	TypeSyncFailed  = "sync_failed"    // Error holds the reason
+	TypeSyncCancel  = "sync_cancelled" // The sync was cancelled on request
+)
+
+// subscriberBuffer is how many events a subscriber may fall behind before events are dropped for it.
+const subscriberBuffer = 64
+
+// Event is a progress update of a repository sync.
+type Event struct {
+	Type         string    `json:"type"`
+	RepositoryID int       `json:"repository_id"`
+	RunID        int       `json:"run_id,omitempty"`
+	Status       string    `json:"status,omitempty"`
+	Path         string    `json:"path,omitempty"`
+	Current      int       `json:"current,omitempty"`
+	Total        int       `json:"total,omitempty"`
+	Error        string    `json:"error,omitempty"`
+	Time         time.Time `json:"time"`
+}
+
+// Subscription receives the events published for one repository, or for all of them.
+type Subscription struct {
+	C            <-chan Event
+	ch           chan Event
+	repositoryID int // 0 subscribes to every repository
+}
+
+// Bus is an in-process publish/subscribe hub for sync events. Publishing never blocks:
+// subscribers that fall too far behind miss events. A Relay extends it to the events of
+// syncs running in other instances.
+type Bus struct {
+	mu      sync.RWMutex
+	subs    map[*Subscription]struct{}
+	forward func(Event) // Set by a Relay; receives the events published in this instance
+}
+
+// NewBus creates a new Bus.
+func NewBus() *Bus {
+	return &Bus{subs: make(map[*Subscription]struct{})}
+}
+
+// Subscribe registers a subscriber for the events of a repository, or of all repositories
+// if repositoryID is 0. Call Unsubscribe when done.
+func (b *Bus) Subscribe(repositoryID int) *Subscription {
+	ch := make(chan Event, subscriberBuffer)
+	sub := &Subscription{C: ch, ch: ch, repositoryID: repositoryID}
+
+	b.mu.Lock()
+	b.subs[sub] = struct{}{}
+	b.mu.Unlock()
+	return sub
+}
+
+// Unsubscribe removes a subscriber and closes its channel.
+func (b *Bus) Unsubscribe(sub *Subscription) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.subs[sub]; ok {
+		delete(b.subs, sub)
+		close(sub.ch)
+	}
+}
+
+// Publish delivers an event to the matching subscribers, and to a Relay if one is started.
+// The event time is set if empty.
+func (b *Bus) Publish(event Event) {
+	if b == nil {
+		return
+	}
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+
+	b.deliver(event)
+	b.mu.RLock()
+	forward := b.forward
+	b.mu.RUnlock()
+	if forward != nil {
+		forward(event)
+	}
+}
+
+// setForward sets the function receiving the events published in this instance.
+func (b *Bus) setForward(forward func(Event)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.forward = forward
+}
+
+// deliver sends an event to the matching subscribers without blocking.
+func (b *Bus) deliver(event Event) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for sub := range b.subs {
+		if sub.repositoryID != 0 && sub.repositoryID != event.RepositoryID {
+			continue
+		}
+		select {
+		case sub.ch <- event:
+		default:
+			log.Printf("Dropping %s event for repository ID %d: subscriber is not keeping up", event.Type, event.RepositoryID)
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package events
+
+import (
+	"testing"
+	"time"
+)
+
+// receive returns the events buffered for a subscription without waiting.
+func receive(sub *Subscription) []Event {
+	var received []Event
+	for {
+		select {
+		case event := <-sub.C:
+			received = append(received, event)
+		default:
+			return received
+		}
+	}
+}
+
+func TestBusPublish(t *testing.T) {
+	bus := NewBus()
+	all := bus.Subscribe(0)
+	repo1 := bus.Subscribe(1)
+	repo2 := bus.Subscribe(2)
+
+	bus.Publish(Event{Type: TypeSyncStarted, RepositoryID: 1})
+	bus.Publish(Event{Type: TypeSyncDone, RepositoryID: 1, Status: "success"})
+	bus.Publish(Event{Type: TypeSyncStarted, RepositoryID: 3})
+
+	if got := receive(all); len(got) != 3 {
+		t.Errorf("subscriber to all repositories got %d events, want 3", len(got))
+	}
+	got := receive(repo1)
+	if len(got) != 2 || got[0].Type != TypeSyncStarted || got[1].Type != TypeSyncDone {
+		t.Errorf("subscriber to repository 1 got %+v, want its two events in order", got)
+	}
+	for _, event := range got {
+		if event.Time.IsZero() {
+			t.Errorf("event %s has no time", event.Type)
+		}
+	}
+	if got := receive(repo2); len(got) != 0 {
+		t.Errorf("subscriber to repository 2 got %+v, want none", got)
+	}
+}
+
+func TestBusPublishKeepsTime(t *testing.T) {
+	bus := NewBus()
+	sub := bus.Subscribe(1)
+	at := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	bus.Publish(Event{Type: TypeListing, RepositoryID: 1, Time: at})
+	if got := receive(sub); len(got) != 1 || !got[0].Time.Equal(at) {
+		t.Errorf("got %+v, want one event at %s", got, at)
+	}
+}
+
+func TestBusSlowSubscriber(t *testing.T) {
+	bus := NewBus()
+	slow := bus.Subscribe(1)
+	fast := bus.Subscribe(1)
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < subscriberBuffer+10; i++ {
+			bus.Publish(Event{Type: TypeFileFetched, RepositoryID: 1, Current: i + 1})
+			<-fast.C
+		}
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Publish blocked on a subscriber that is not reading")
+	}
+
+	got := receive(slow)
+	if len(got) != subscriberBuffer {
+		t.Fatalf("slow subscriber got %d events, want the first %d", len(got), subscriberBuffer)
+	}
+	if got[len(got)-1].Current != subscriberBuffer {
+		t.Errorf("last event kept is %d, want %d", got[len(got)-1].Current, subscriberBuffer)
+	}
+}
+
+func TestBusUnsubscribe(t *testing.T) {
+	bus := NewBus()
+	sub := bus.Subscribe(0)
+	bus.Unsubscribe(sub)
+	bus.Unsubscribe(sub) // Unsubscribing twice is harmless
+
+	if _, ok := <-sub.C; ok {
+		t.Error("channel still open after Unsubscribe")
+	}
+	bus.Publish(Event{Type: TypeSyncStarted, RepositoryID: 1}) // Must not send on the closed channel
+}
+
+func TestNilBusPublish(t *testing.T) {
+	var bus *Bus
+	bus.Publish(Event{Type: TypeSyncStarted, RepositoryID: 1})
+}
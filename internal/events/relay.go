@@ -0,0 +1,206 @@
+package events
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+)
+
+const (
+	// relayBuffer is how many events may wait to be sent to other instances before events
+	// are dropped.
+	relayBuffer = 256
+	// relayProgressInterval is the shortest time between two file progress events of a
+	// repository relayed to other instances.
+	relayProgressInterval = time.Second
+	// relayRetryDelay is how long to wait before listening again after the listener failed.
+	relayRetryDelay = 5 * time.Second
+	// relaySendTimeout bounds the time spent sending one event.
+	relaySendTimeout = 5 * time.Second
+	// maxRelayPayload is the largest payload sent; PostgreSQL NOTIFY payloads must be
+	// shorter than 8000 bytes.
+	maxRelayPayload = 7900
+	// maxRelayError is the length error messages are cut to when a payload is too large.
+	maxRelayError = 1000
+)
+
+// Notifier carries event payloads between the instances sharing a database, such as
+// PostgreSQL NOTIFY and LISTEN.
+type Notifier interface {
+	// NotifySyncEvent sends a payload to every listening instance, including this one.
+	NotifySyncEvent(ctx context.Context, payload string) error
+	// ListenSyncEvents calls handle with each payload received until ctx is done or
+	// listening fails.
+	ListenSyncEvents(ctx context.Context, handle func(payload string)) error
+}
+
+// relayMessage is the payload of a relayed event.
+type relayMessage struct {
+	Origin string `json:"origin"` // Relay that sent the event, so it skips its own events
+	Event  Event  `json:"event"`
+}
+
+// Relay forwards the events published on a Bus to the other instances, and delivers the
+// events they publish to the Bus, so that clients see the progress of a sync whichever
+// instance runs it. Every event but file progress is relayed; file progress is relayed at
+// most once per relayProgressInterval per repository, and for the last file. Events are
+// dropped rather than delayed, and events sent while the listener reconnects are missed.
+type Relay struct {
+	bus      *Bus
+	notifier Notifier
+	origin   string
+	out      chan Event
+
+	progressMu   sync.Mutex
+	lastProgress map[int]time.Time // Time the last file progress event was relayed, by repository ID
+
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewRelay creates a Relay between bus and the other instances reached through notifier.
+func NewRelay(bus *Bus, notifier Notifier) *Relay {
+	origin := make([]byte, 8)
+	_, _ = rand.Read(origin)
+	return &Relay{
+		bus:      bus,
+		notifier: notifier,
+		origin:   hex.EncodeToString(origin),
+		out:      make(chan Event, relayBuffer),
+
+		lastProgress: make(map[int]time.Time),
+	}
+}
+
+// Start begins relaying events.
+func (r *Relay) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	r.cancel = cancel
+	r.bus.setForward(r.enqueue)
+
+	r.wg.Add(2)
+	go r.send(ctx)
+	go r.listen(ctx)
+	log.Println("Sync event relay started.")
+}
+
+// Stop stops relaying events and waits for the relay to finish.
+func (r *Relay) Stop() {
+	r.bus.setForward(nil)
+	r.cancel()
+	r.wg.Wait()
+	log.Println("Sync event relay stopped.")
+}
+
+// enqueue queues an event published in this instance for the other instances, unless it
+// is file progress to skip.
+func (r *Relay) enqueue(event Event) {
+	if !r.shouldRelay(event) {
+		return
+	}
+	select {
+	case r.out <- event:
+	default:
+		log.Printf("Dropping %s event for repository ID %d: relay is not keeping up", event.Type, event.RepositoryID)
+	}
+}
+
+// shouldRelay reports whether an event is relayed, throttling file progress events.
+func (r *Relay) shouldRelay(event Event) bool {
+	r.progressMu.Lock()
+	defer r.progressMu.Unlock()
+
+	switch event.Type {
+	case TypeFileFetched:
+		// Throttled below
+	case TypeSyncDone, TypeSyncFailed, TypeSyncCancel:
+		delete(r.lastProgress, event.RepositoryID)
+		return true
+	default:
+		return true
+	}
+	if event.Current == event.Total {
+		return true // Clients waiting for the files to be done see the last one
+	}
+	now := time.Now()
+	if last, ok := r.lastProgress[event.RepositoryID]; ok && now.Sub(last) < relayProgressInterval {
+		return false
+	}
+	r.lastProgress[event.RepositoryID] = now
+	return true
+}
+
+// send sends the queued events until ctx is done.
+func (r *Relay) send(ctx context.Context) {
+	defer r.wg.Done()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event := <-r.out:
+			payload, ok := r.encode(event)
+			if !ok {
+				log.Printf("Not relaying %s event for repository ID %d: payload too large", event.Type, event.RepositoryID)
+				continue
+			}
+			sendCtx, cancel := context.WithTimeout(ctx, relaySendTimeout)
+			if err := r.notifier.NotifySyncEvent(sendCtx, payload); err != nil && ctx.Err() == nil {
+				log.Printf("Error relaying %s event for repository ID %d: %v", event.Type, event.RepositoryID, err)
+			}
+			cancel()
+		}
+	}
+}
+
+// encode returns the payload of an event, cutting its error message if the payload would
+// be too large.
+func (r *Relay) encode(event Event) (string, bool) {
+	for {
+		payload, err := json.Marshal(relayMessage{Origin: r.origin, Event: event})
+		if err != nil {
+			return "", false
+		}
+		if len(payload) <= maxRelayPayload {
+			return string(payload), true
+		}
+		if len(event.Error) <= maxRelayError {
+			return "", false
+		}
+		event.Error = event.Error[:maxRelayError]
+	}
+}
+
+// listen delivers the events of other instances to the bus until ctx is done, listening
+// again after a delay whenever the listener fails.
+func (r *Relay) listen(ctx context.Context) {
+	defer r.wg.Done()
+	for {
+		err := r.notifier.ListenSyncEvents(ctx, r.receive)
+		if ctx.Err() != nil {
+			return
+		}
+		log.Printf("Sync event listener failed: %v. Listening again in %s.", err, relayRetryDelay)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(relayRetryDelay):
+		}
+	}
+}
+
+// receive delivers a relayed event to the bus, unless this relay sent it.
+func (r *Relay) receive(payload string) {
+	var message relayMessage
+	if err := json.Unmarshal([]byte(payload), &message); err != nil {
+		log.Printf("Ignoring malformed relayed sync event: %v", err)
+		return
+	}
+	if message.Origin == r.origin {
+		return // Already delivered locally when it was published
+	}
+	r.bus.deliver(message.Event)
+}
@@ -0,0 +1,201 @@
+package events
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeNotifier delivers every payload to all listeners, like NOTIFY on a shared database.
+type fakeNotifier struct {
+	mu        sync.Mutex
+	listeners map[int]func(string)
+	next      int
+	payloads  []string
+}
+
+func newFakeNotifier() *fakeNotifier {
+	return &fakeNotifier{listeners: make(map[int]func(string))}
+}
+
+func (n *fakeNotifier) NotifySyncEvent(ctx context.Context, payload string) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.payloads = append(n.payloads, payload)
+	for _, handle := range n.listeners {
+		handle(payload)
+	}
+	return nil
+}
+
+func (n *fakeNotifier) ListenSyncEvents(ctx context.Context, handle func(string)) error {
+	n.mu.Lock()
+	id := n.next
+	n.next++
+	n.listeners[id] = handle
+	n.mu.Unlock()
+
+	<-ctx.Done()
+	n.mu.Lock()
+	delete(n.listeners, id)
+	n.mu.Unlock()
+	return ctx.Err()
+}
+
+func (n *fakeNotifier) waitListeners(t *testing.T, count int) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		n.mu.Lock()
+		listening := len(n.listeners)
+		n.mu.Unlock()
+		if listening == count {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%d listeners, want %d", listening, count)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func waitEvent(t *testing.T, sub *Subscription) Event {
+	t.Helper()
+	select {
+	case event := <-sub.C:
+		return event
+	case <-time.After(5 * time.Second):
+		t.Fatal("no event received")
+		return Event{}
+	}
+}
+
+func TestRelay(t *testing.T) {
+	notifier := newFakeNotifier()
+	local, remote := NewBus(), NewBus()
+	localRelay, remoteRelay := NewRelay(local, notifier), NewRelay(remote, notifier)
+	localRelay.Start()
+	defer localRelay.Stop()
+	remoteRelay.Start()
+	defer remoteRelay.Stop()
+	notifier.waitListeners(t, 2)
+
+	localSub := local.Subscribe(1)
+	remoteSub := remote.Subscribe(1)
+	remoteOther := remote.Subscribe(2)
+
+	local.Publish(Event{Type: TypeFileFetched, RepositoryID: 1, Path: "docs/index.md", Current: 1, Total: 2})
+
+	if got := waitEvent(t, remoteSub); got.Type != TypeFileFetched || got.Path != "docs/index.md" || got.Time.IsZero() {
+		t.Errorf("remote subscriber got %+v", got)
+	}
+	if got := waitEvent(t, localSub); got.Type != TypeFileFetched {
+		t.Errorf("local subscriber got %+v", got)
+	}
+	// The relayed copy of its own event must not reach the publishing instance again
+	time.Sleep(10 * time.Millisecond)
+	if got := receive(localSub); len(got) != 0 {
+		t.Errorf("local subscriber got %+v again", got)
+	}
+	if got := receive(remoteOther); len(got) != 0 {
+		t.Errorf("subscriber to another repository got %+v", got)
+	}
+}
+
+func TestRelayThrottlesProgress(t *testing.T) {
+	notifier := newFakeNotifier()
+	local, remote := NewBus(), NewBus()
+	localRelay, remoteRelay := NewRelay(local, notifier), NewRelay(remote, notifier)
+	localRelay.Start()
+	defer localRelay.Stop()
+	remoteRelay.Start()
+	defer remoteRelay.Stop()
+	notifier.waitListeners(t, 2)
+
+	remoteSub := remote.Subscribe(1)
+	const files = 100
+	local.Publish(Event{Type: TypeSyncStarted, RepositoryID: 1})
+	local.Publish(Event{Type: TypeFilesFound, RepositoryID: 1, Total: files})
+	for i := 1; i <= files; i++ {
+		local.Publish(Event{Type: TypeFileFetched, RepositoryID: 1, Current: i, Total: files})
+	}
+	local.Publish(Event{Type: TypeSyncDone, RepositoryID: 1, Status: "success"})
+
+	// All coarse events, and of the file progress only the first and the last file
+	want := []Event{
+		{Type: TypeSyncStarted},
+		{Type: TypeFilesFound, Total: files},
+		{Type: TypeFileFetched, Current: 1, Total: files},
+		{Type: TypeFileFetched, Current: files, Total: files},
+		{Type: TypeSyncDone, Status: "success"},
+	}
+	for _, w := range want {
+		got := waitEvent(t, remoteSub)
+		if got.Type != w.Type || got.Current != w.Current || got.Total != w.Total || got.Status != w.Status {
+			t.Fatalf("remote subscriber got %+v, want %+v", got, w)
+		}
+	}
+	time.Sleep(10 * time.Millisecond)
+	if got := receive(remoteSub); len(got) != 0 {
+		t.Errorf("remote subscriber got %d more events, want none", len(got))
+	}
+
+	notifier.mu.Lock()
+	relayed := len(notifier.payloads)
+	notifier.mu.Unlock()
+	if relayed != len(want) {
+		t.Errorf("relayed %d events, want %d", relayed, len(want))
+	}
+}
+
+func TestRelayStop(t *testing.T) {
+	notifier := newFakeNotifier()
+	bus := NewBus()
+	relay := NewRelay(bus, notifier)
+	relay.Start()
+	notifier.waitListeners(t, 1)
+	relay.Stop()
+	notifier.waitListeners(t, 0)
+
+	sub := bus.Subscribe(1)
+	bus.Publish(Event{Type: TypeSyncStarted, RepositoryID: 1})
+	if got := receive(sub); len(got) != 1 {
+		t.Errorf("local subscriber got %d events after Stop, want 1", len(got))
+	}
+	notifier.mu.Lock()
+	defer notifier.mu.Unlock()
+	if len(notifier.payloads) != 0 {
+		t.Errorf("relayed %d events after Stop, want none", len(notifier.payloads))
+	}
+}
+
+func TestRelayReceiveMalformed(t *testing.T) {
+	bus := NewBus()
+	relay := NewRelay(bus, newFakeNotifier())
+	sub := bus.Subscribe(0)
+	relay.receive("not json")
+	if got := receive(sub); len(got) != 0 {
+		t.Errorf("got %+v from a malformed payload", got)
+	}
+}
+
+func TestRelayEncodeLargeError(t *testing.T) {
+	relay := NewRelay(NewBus(), newFakeNotifier())
+
+	payload, ok := relay.encode(Event{Type: TypeSyncFailed, RepositoryID: 1, Error: strings.Repeat("x", 10000)})
+	if !ok {
+		t.Fatal("encode() failed for an event with a long error")
+	}
+	if len(payload) > maxRelayPayload {
+		t.Errorf("payload is %d bytes, want at most %d", len(payload), maxRelayPayload)
+	}
+	if !strings.Contains(payload, strings.Repeat("x", maxRelayError)) {
+		t.Error("payload lost the start of the error")
+	}
+
+	if _, ok := relay.encode(Event{Type: TypeFileFetched, RepositoryID: 1, Path: strings.Repeat("p", 10000)}); ok {
+		t.Error("encode() accepted a payload that cannot be shortened")
+	}
+}
@@ -0,0 +1,75 @@
+package format
+
+import (
+	"io"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Default is the format used when none is requested.
+const Default = "markdown"
+
+// File is a single synced file.
+type File struct {
+	Path    string `json:"path"`
+	SHA     string `json:"sha"` // Git blob SHA of the content
+	Content string `json:"content"`
+}
+
+// Provenance describes where a document's files came from.
+type Provenance struct {
+	Repository string    `json:"repository"` // Repository URL
+	Ref        string    `json:"ref"`        // Branch, tag or commit SHA that was synced
+	RefMode    string    `json:"ref_mode"`
+	Commit     string    `json:"commit"`
+	SyncedAt   time.Time `json:"synced_at"`
+	Files      int       `json:"files"`
+}
+
+// Document is the content of a repository to render: its files, in order, and optionally
+// their provenance.
+type Document struct {
+	Provenance *Provenance
+	Files      []File
+}
+
+// Formatter renders a Document in one download format.
+type Formatter interface {
+	Extension() string   // Filename extension, without the dot
+	ContentType() string // MIME type, including the charset if any
+	Write(w io.Writer, doc *Document) error
+}
+
+var (
+	registryMu sync.RWMutex
+	registry   = make(map[string]Formatter)
+)
+
+// Register makes a formatter available under name, replacing any formatter registered
+// under the same name.
+func Register(name string, f Formatter) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+	registry[name] = f
+}
+
+// Get returns the formatter registered under name.
+func Get(name string) (Formatter, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	f, ok := registry[name]
+	return f, ok
+}
+
+// Names returns the names of the registered formatters, sorted.
+func Names() []string {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,181 @@
+package format
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+var testProvenance = &Provenance{
+	Repository: "https://github.com/owner/repo",
+	Ref:        "v1.2.0",
+	RefMode:    "tag",
+	Commit:     "abc123",
+	SyncedAt:   time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC),
+	Files:      2,
+}
+
+var testFiles = []File{
+	{Path: "docs/a.md", SHA: "sha-a", Content: "# A <tag> & \"quoted\"\n"},
+	{Path: "docs/b.md", SHA: "sha-b", Content: "B ]]> end"},
+}
+
+func render(t *testing.T, name string, doc *Document) string {
+	t.Helper()
+	f, ok := Get(name)
+	if !ok {
+		t.Fatalf("format %q is not registered", name)
+	}
+	var out bytes.Buffer
+	if err := f.Write(&out, doc); err != nil {
+		t.Fatalf("Write(%s) returned error: %v", name, err)
+	}
+	return out.String()
+}
+
+func TestRegistry(t *testing.T) {
+	want := []string{"json", "jsonl", "markdown", "plain", "xml"}
+	if got := Names(); !slices.Equal(got, want) {
+		t.Errorf("Names() = %q, want %q", got, want)
+	}
+	if _, ok := Get(Default); !ok {
+		t.Errorf("default format %q is not registered", Default)
+	}
+	if _, ok := Get("yaml"); ok {
+		t.Error("Get(yaml) found a formatter")
+	}
+
+	extensions := map[string]string{"json": "json", "jsonl": "jsonl", "markdown": "md", "plain": "txt", "xml": "xml"}
+	for name, ext := range extensions {
+		f, _ := Get(name)
+		if f.Extension() != ext {
+			t.Errorf("%s extension = %q, want %q", name, f.Extension(), ext)
+		}
+		if !strings.Contains(f.ContentType(), "charset=utf-8") {
+			t.Errorf("%s content type %q has no charset", name, f.ContentType())
+		}
+	}
+}
+
+func TestMarkdown(t *testing.T) {
+	got := render(t, "markdown", &Document{Files: testFiles})
+	want := MarkdownSection("docs/a.md", testFiles[0].Content) + MarkdownSection("docs/b.md", testFiles[1].Content)
+	if got != want {
+		t.Errorf("markdown =\n%q\nwant\n%q", got, want)
+	}
+	if section := MarkdownSection("a.md", "x"); section != "---\nFile: a.md\n---\n\nx\n\n\n" {
+		t.Errorf("MarkdownSection = %q", section)
+	}
+
+	got = render(t, "markdown", &Document{Provenance: testProvenance, Files: testFiles})
+	header := "---\nRepository: https://github.com/owner/repo\nRef: v1.2.0 (tag)\nCommit: abc123\nSynced at: 2024-05-01T12:00:00Z\nFiles: 2\n---\n\n"
+	if got != header+want {
+		t.Errorf("markdown with provenance =\n%q\nwant\n%q", got, header+want)
+	}
+}
+
+func TestPlain(t *testing.T) {
+	got := render(t, "plain", &Document{Files: testFiles})
+	want := "# A <tag> & \"quoted\"\nB ]]> end\n"
+	if got != want {
+		t.Errorf("plain = %q, want %q", got, want)
+	}
+}
+
+func TestXML(t *testing.T) {
+	out := render(t, "xml", &Document{Provenance: testProvenance, Files: testFiles})
+
+	var parsed struct {
+		Repository string `xml:"repository,attr"`
+		Commit     string `xml:"commit,attr"`
+		Files      string `xml:"files,attr"`
+		Documents  []struct {
+			Path    string `xml:"path,attr"`
+			SHA     string `xml:"sha,attr"`
+			Content string `xml:",chardata"`
+		} `xml:"document"`
+	}
+	if err := xml.Unmarshal([]byte(out), &parsed); err != nil {
+		t.Fatalf("xml output does not parse: %v\n%s", err, out)
+	}
+	if parsed.Repository != testProvenance.Repository || parsed.Commit != "abc123" || parsed.Files != "2" {
+		t.Errorf("xml provenance = %+v", parsed)
+	}
+	if len(parsed.Documents) != len(testFiles) {
+		t.Fatalf("xml has %d documents, want %d", len(parsed.Documents), len(testFiles))
+	}
+	for i, doc := range parsed.Documents {
+		file := testFiles[i]
+		// Content is framed by a newline on each side
+		if doc.Path != file.Path || doc.SHA != file.SHA || doc.Content != "\n"+file.Content+"\n" {
+			t.Errorf("xml document %d = %+v, want %+v", i, doc, file)
+		}
+	}
+
+	escaped := render(t, "xml", &Document{Files: []File{{Path: `a "b" <c>.md`, SHA: "s", Content: "x"}}})
+	if !strings.Contains(escaped, `path="a &quot;b&quot; &lt;c&gt;.md"`) {
+		t.Errorf("xml path attribute not escaped: %s", escaped)
+	}
+}
+
+func TestJSON(t *testing.T) {
+	var files []File
+	if err := json.Unmarshal([]byte(render(t, "json", &Document{Files: testFiles})), &files); err != nil {
+		t.Fatalf("json output does not parse: %v", err)
+	}
+	if !slices.Equal(files, testFiles) {
+		t.Errorf("json files = %+v, want %+v", files, testFiles)
+	}
+
+	if got := render(t, "json", &Document{}); got != "[]\n" {
+		t.Errorf("json of no files = %q, want %q", got, "[]\n")
+	}
+
+	out := render(t, "json", &Document{Provenance: testProvenance, Files: testFiles})
+	var withProvenance struct {
+		Provenance Provenance `json:"provenance"`
+		Files      []File     `json:"files"`
+	}
+	if err := json.Unmarshal([]byte(out), &withProvenance); err != nil {
+		t.Fatalf("json output with provenance does not parse: %v", err)
+	}
+	if withProvenance.Provenance != *testProvenance || !slices.Equal(withProvenance.Files, testFiles) {
+		t.Errorf("json with provenance = %+v", withProvenance)
+	}
+	if strings.Contains(out, `\u003c`) {
+		t.Errorf("json escapes HTML characters: %s", out)
+	}
+}
+
+func TestJSONL(t *testing.T) {
+	out := render(t, "jsonl", &Document{Provenance: testProvenance, Files: testFiles})
+	lines := strings.Split(strings.TrimSuffix(out, "\n"), "\n")
+	if len(lines) != 1+len(testFiles) {
+		t.Fatalf("jsonl has %d lines, want %d:\n%s", len(lines), 1+len(testFiles), out)
+	}
+
+	var first map[string]Provenance
+	if err := json.Unmarshal([]byte(lines[0]), &first); err != nil {
+		t.Fatalf("jsonl provenance line does not parse: %v", err)
+	}
+	if first["provenance"] != *testProvenance {
+		t.Errorf("jsonl provenance = %+v", first)
+	}
+	for i, line := range lines[1:] {
+		var file File
+		if err := json.Unmarshal([]byte(line), &file); err != nil {
+			t.Fatalf("jsonl line %d does not parse: %v", i+2, err)
+		}
+		if file != testFiles[i] {
+			t.Errorf("jsonl file %d = %+v, want %+v", i, file, testFiles[i])
+		}
+	}
+
+	if got := render(t, "jsonl", &Document{}); got != "" {
+		t.Errorf("jsonl of no files = %q, want empty", got)
+	}
+}
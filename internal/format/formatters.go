@@ -0,0 +1,178 @@
+package format
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func init() {
+	Register("markdown", markdownFormatter{})
+	Register("xml", xmlFormatter{})
+	Register("json", jsonFormatter{})
+	Register("jsonl", jsonlFormatter{})
+	Register("plain", plainFormatter{})
+}
+
+// MarkdownSection renders a single file with its separator header, as stored in the
+// aggregated content of a repository.
+func MarkdownSection(path, content string) string {
+	var section strings.Builder
+	section.WriteString("---\n")
+	section.WriteString(fmt.Sprintf("File: %s\n", path))
+	section.WriteString("---\n\n")
+	section.WriteString(content)
+	section.WriteString("\n\n\n") // Add extra newlines between files
+	return section.String()
+}
+
+// provenanceLines renders the provenance as "Key: value" lines.
+func provenanceLines(p *Provenance) string {
+	syncedAt := ""
+	if !p.SyncedAt.IsZero() {
+		syncedAt = p.SyncedAt.UTC().Format(time.RFC3339)
+	}
+	var lines strings.Builder
+	fmt.Fprintf(&lines, "Repository: %s\n", p.Repository)
+	fmt.Fprintf(&lines, "Ref: %s (%s)\n", p.Ref, p.RefMode)
+	fmt.Fprintf(&lines, "Commit: %s\n", p.Commit)
+	fmt.Fprintf(&lines, "Synced at: %s\n", syncedAt)
+	fmt.Fprintf(&lines, "Files: %d\n", p.Files)
+	return lines.String()
+}
+
+// markdownFormatter renders files with "---\nFile: <path>\n---" separators, the format of
+// the stored aggregated content.
+type markdownFormatter struct{}
+
+func (markdownFormatter) Extension() string   { return "md" }
+func (markdownFormatter) ContentType() string { return "text/markdown; charset=utf-8" }
+
+func (markdownFormatter) Write(w io.Writer, doc *Document) error {
+	bw := bufio.NewWriter(w)
+	if doc.Provenance != nil {
+		// Framed like the file sections that follow it
+		bw.WriteString("---\n" + provenanceLines(doc.Provenance) + "---\n\n")
+	}
+	for _, file := range doc.Files {
+		bw.WriteString(MarkdownSection(file.Path, file.Content))
+	}
+	return bw.Flush()
+}
+
+// xmlFormatter wraps each file in a <document path="..."> element inside <documents>.
+type xmlFormatter struct{}
+
+func (xmlFormatter) Extension() string   { return "xml" }
+func (xmlFormatter) ContentType() string { return "application/xml; charset=utf-8" }
+
+var (
+	xmlTextEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;")
+	xmlAttrEscaper = strings.NewReplacer("&", "&amp;", "<", "&lt;", ">", "&gt;", `"`, "&quot;", "\n", "&#xA;")
+)
+
+func (xmlFormatter) Write(w io.Writer, doc *Document) error {
+	bw := bufio.NewWriter(w)
+	bw.WriteString("<documents")
+	if p := doc.Provenance; p != nil {
+		syncedAt := ""
+		if !p.SyncedAt.IsZero() {
+			syncedAt = p.SyncedAt.UTC().Format(time.RFC3339)
+		}
+		for _, attr := range [][2]string{
+			{"repository", p.Repository},
+			{"ref", p.Ref},
+			{"ref_mode", p.RefMode},
+			{"commit", p.Commit},
+			{"synced_at", syncedAt},
+			{"files", strconv.Itoa(p.Files)},
+		} {
+			fmt.Fprintf(bw, ` %s="%s"`, attr[0], xmlAttrEscaper.Replace(attr[1]))
+		}
+	}
+	bw.WriteString(">\n")
+	for _, file := range doc.Files {
+		// Text is escaped rather than wrapped in CDATA, which cannot hold "]]>"
+		fmt.Fprintf(bw, "<document path=\"%s\" sha=\"%s\">\n", xmlAttrEscaper.Replace(file.Path), xmlAttrEscaper.Replace(file.SHA))
+		xmlTextEscaper.WriteString(bw, file.Content)
+		bw.WriteString("\n</document>\n")
+	}
+	bw.WriteString("</documents>\n")
+	return bw.Flush()
+}
+
+// jsonFormatter renders an array of {path, sha, content} objects, or, with provenance, an
+// object holding the provenance and that array as "files".
+type jsonFormatter struct{}
+
+func (jsonFormatter) Extension() string   { return "json" }
+func (jsonFormatter) ContentType() string { return "application/json; charset=utf-8" }
+
+func (jsonFormatter) Write(w io.Writer, doc *Document) error {
+	files := doc.Files
+	if files == nil {
+		files = []File{} // Encode an empty array rather than null
+	}
+	var v any = files
+	if doc.Provenance != nil {
+		v = struct {
+			Provenance *Provenance `json:"provenance"`
+			Files      []File      `json:"files"`
+		}{doc.Provenance, files}
+	}
+	return newJSONEncoder(w).Encode(v)
+}
+
+// jsonlFormatter renders one {path, sha, content} object per line, preceded by a
+// {"provenance": {...}} line if requested.
+type jsonlFormatter struct{}
+
+func (jsonlFormatter) Extension() string   { return "jsonl" }
+func (jsonlFormatter) ContentType() string { return "application/jsonl; charset=utf-8" }
+
+func (jsonlFormatter) Write(w io.Writer, doc *Document) error {
+	bw := bufio.NewWriter(w)
+	enc := newJSONEncoder(bw)
+	if doc.Provenance != nil {
+		if err := enc.Encode(map[string]*Provenance{"provenance": doc.Provenance}); err != nil {
+			return err
+		}
+	}
+	for _, file := range doc.Files {
+		if err := enc.Encode(file); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// newJSONEncoder returns an encoder that leaves <, > and & in file content as they are.
+func newJSONEncoder(w io.Writer) *json.Encoder {
+	enc := json.NewEncoder(w)
+	enc.SetEscapeHTML(false)
+	return enc
+}
+
+// plainFormatter concatenates the file contents without any framing, each ending in a newline.
+type plainFormatter struct{}
+
+func (plainFormatter) Extension() string   { return "txt" }
+func (plainFormatter) ContentType() string { return "text/plain; charset=utf-8" }
+
+func (plainFormatter) Write(w io.Writer, doc *Document) error {
+	bw := bufio.NewWriter(w)
+	if doc.Provenance != nil {
+		bw.WriteString(provenanceLines(doc.Provenance) + "\n")
+	}
+	for _, file := range doc.Files {
+		bw.WriteString(file.Content)
+		if !strings.HasSuffix(file.Content, "\n") {
+			bw.WriteString("\n")
+		}
+	}
+	return bw.Flush()
+}
@@ -16,6 +16,7 @@ import (
 // Client wraps the go-github client.
 type Client struct {
 	*github.Client
+	rateLimits *rateLimitTransport // Tracks the rate limit budget from every response
 }
 
 // NewClient creates a new GitHub API client authenticated with a Personal Access Token (PAT).
@@ -23,7 +24,8 @@ func NewClient(ctx context.Context, token string) (*Client, error) {
 	if token == "" {
 		log.Println("Warning: No GITHUB_TOKEN provided. GitHub API interactions will be unauthenticated and rate-limited.")
 		// Return a client without authentication
-		return &Client{github.NewClient(nil)}, nil
+		httpClient, rateLimits := newHTTPClient(nil)
+		return &Client{Client: github.NewClient(httpClient), rateLimits: rateLimits}, nil
 	}
 
 	ts := oauth2.StaticTokenSource(
@@ -31,7 +33,8 @@ func NewClient(ctx context.Context, token string) (*Client, error) {
 	)
 	tc := oauth2.NewClient(ctx, ts)
 
-	client := github.NewClient(tc)
+	httpClient, rateLimits := newHTTPClient(tc)
+	client := github.NewClient(httpClient)
 
 	// Optional: Verify authentication by getting the current user
 	// user, _, err := client.Users.Get(ctx, "")
@@ -44,7 +47,19 @@ func NewClient(ctx context.Context, token string) (*Client, error) {
 	// }
 
 	log.Println("GitHub client initialized successfully.")
-	return &Client{client}, nil
+	return &Client{Client: client, rateLimits: rateLimits}, nil
+}
+
+// RateLimitStatus returns the last known GitHub API rate limit budget.
+func (c *Client) RateLimitStatus() RateLimitStatus {
+	return c.rateLimits.Status()
+}
+
+// waitOnRateLimit marks ctx so that go-github waits for the rate limit to reset rather than
+// failing with a *github.RateLimitError once a response reported the budget exhausted;
+// go-github checks that before the request reaches the rate limit transport.
+func waitOnRateLimit(ctx context.Context) context.Context {
+	return context.WithValue(ctx, github.SleepUntilPrimaryRateLimitResetWhenRateLimited, true)
 }
 
 // ParseRepoURL extracts the owner and repository name from a GitHub URL.
@@ -74,84 +89,86 @@ func ParseRepoURL(repoURL string) (owner, repo string, err error) {
 	return owner, repo, nil
 }
 
-// FileInfo holds path and SHA for a file in the repository.
+// FileInfo holds path, SHA and size for a file in the repository.
 type FileInfo struct {
 	Path string
 	SHA  string
+	Size int // Size in bytes as reported by the Git tree; 0 if unknown
 }
 
-// GetRepoContentsRecursive fetches all file entries recursively starting from a given path.
-// It returns a flat list of FileInfo for files only.
+// GetRepoContentsRecursive lists all files under a given path using the Git Trees API.
+// It returns a flat list of FileInfo for files only. Use Trees.ListFiles to share the tree
+// lookups with other paths of the same commit.
 func (c *Client) GetRepoContentsRecursive(ctx context.Context, owner, repo, path string, branch string) ([]FileInfo, error) {
-	var allFiles []FileInfo
-
-	queue := []string{path} // Use a queue for breadth-first traversal, though depth-first works too
+	return c.NewTrees(owner, repo, branch).ListFiles(ctx, path)
+}
 
-	var opts *github.RepositoryContentGetOptions
-	if branch != "" {
-		opts = &github.RepositoryContentGetOptions{Ref: branch}
+// walkTree lists files by fetching each subtree non-recursively.
+// It is used when a recursive tree listing is truncated by GitHub.
+func (c *Client) walkTree(ctx context.Context, owner, repo, treeSHA, prefix string) ([]FileInfo, error) {
+	type pendingTree struct {
+		sha    string
+		prefix string
 	}
 
+	var allFiles []FileInfo
+	queue := []pendingTree{{sha: treeSHA, prefix: prefix}}
+
 	for len(queue) > 0 {
-		currentPath := queue[0]
+		current := queue[0]
 		queue = queue[1:]
 
-		// Get contents of the current directory path
-		_, dirContents, _, err := c.Repositories.GetContents(ctx, owner, repo, currentPath, opts)
+		tree, _, err := c.Git.GetTree(ctx, owner, repo, current.sha, false)
 		if err != nil {
-			// Handle common errors like 404 Not Found gracefully
-			var ghErr *github.ErrorResponse
-			if errors.As(err, &ghErr) && ghErr.Response.StatusCode == http.StatusNotFound {
-				log.Printf("Warning: Path not found in repo %s/%s (branch: %s): %s", owner, repo, branch, currentPath)
-				continue // Skip this path if not found
-			}
-			log.Printf("Error getting contents for %s/%s path %s (branch: %s): %v", owner, repo, currentPath, branch, err)
-			return nil, fmt.Errorf("failed to get contents for path '%s' (branch: %s): %w", currentPath, branch, err)
+			log.Printf("Error getting subtree %s for %s/%s path %s: %v", current.sha, owner, repo, current.prefix, err)
+			return nil, fmt.Errorf("failed to get subtree for path '%s': %w", current.prefix, err)
 		}
 
-		if dirContents == nil {
-			// This might happen if the path points directly to a file initially, handle it
-			fileContent, _, _, err := c.Repositories.GetContents(ctx, owner, repo, currentPath, opts)
-			if err == nil && fileContent != nil && fileContent.GetType() == "file" {
-				allFiles = append(allFiles, FileInfo{Path: *fileContent.Path, SHA: *fileContent.SHA})
-				continue // Processed the single file path
+		for _, entry := range tree.Entries {
+			if entry.GetType() == "tree" {
+				queue = append(queue, pendingTree{sha: entry.GetSHA(), prefix: joinTreePath(current.prefix, entry.GetPath())})
+				continue
 			}
-			// If it's not a file or error occurred, log and continue
-			log.Printf("Warning: Received nil directory contents for path %s, skipping.", currentPath)
-			continue
-		}
-
-
-		for _, item := range dirContents {
-			itemPath := *item.Path // Dereference pointer safely
-			itemType := *item.Type // Dereference pointer safely
-
-			if itemType == "dir" {
-				queue = append(queue, itemPath) // Add subdirectory to the queue
-			} else if itemType == "file" {
-				if item.SHA == nil || item.Path == nil {
-					log.Printf("Warning: Skipping file with missing SHA or Path in %s", currentPath)
-					continue
-				}
-				allFiles = append(allFiles, FileInfo{Path: itemPath, SHA: *item.SHA})
+			if file, ok := treeEntryToFileInfo(entry, current.prefix); ok {
+				allFiles = append(allFiles, file)
 			}
-			// Ignore other types like "symlink", "submodule" for now
 		}
 	}
 
 	return allFiles, nil
 }
 
+// treeEntryToFileInfo converts a tree entry into a FileInfo with its full repository path.
+// Only regular files are returned; trees, submodules and symlinks are ignored.
+func treeEntryToFileInfo(entry *github.TreeEntry, prefix string) (FileInfo, bool) {
+	if entry.GetType() != "blob" || entry.GetMode() == "120000" { // 120000 is a symlink
+		return FileInfo{}, false
+	}
+	if entry.SHA == nil || entry.Path == nil {
+		log.Printf("Warning: Skipping tree entry with missing SHA or Path under %s", prefix)
+		return FileInfo{}, false
+	}
+	return FileInfo{Path: joinTreePath(prefix, entry.GetPath()), SHA: entry.GetSHA(), Size: entry.GetSize()}, true
+}
+
+// joinTreePath joins a directory prefix and a relative tree path.
+func joinTreePath(prefix, path string) string {
+	if prefix == "" {
+		return path
+	}
+	return prefix + "/" + path
+}
 
 // GetFileContent fetches the raw content of a specific file using its path.
 func (c *Client) GetFileContent(ctx context.Context, owner, repo, path string, branch string) (string, error) {
+	ctx = waitOnRateLimit(ctx)
 	var opts *github.RepositoryContentGetOptions
 	if branch != "" {
 		opts = &github.RepositoryContentGetOptions{Ref: branch}
 	}
 
-	// GetContents can retrieve file content directly for smaller files.
-	// For larger files, GetBlob might be necessary, but GetContents is simpler.
+	// GetContents returns file content inline only for files up to 1 MB.
+	// Larger files come back without content and are fetched via the Blobs API below.
 	fileContent, _, _, err := c.Repositories.GetContents(ctx, owner, repo, path, opts)
 	if err != nil {
 		log.Printf("Error getting file content for %s/%s path %s (branch: %s): %v", owner, repo, path, branch, err)
@@ -167,6 +184,11 @@ func (c *Client) GetFileContent(ctx context.Context, owner, repo, path string, b
 		return "", fmt.Errorf("path is not a file: %s (branch: %s)", path, branch)
 	}
 
+	if fileContent.GetEncoding() == "none" || (fileContent.Content == nil && fileContent.GetSize() > 0) {
+		log.Printf("Content of %s (branch: %s) not returned inline (size: %d bytes), fetching blob %s", path, branch, fileContent.GetSize(), fileContent.GetSHA())
+		return c.GetBlobContent(ctx, owner, repo, fileContent.GetSHA())
+	}
+
 	content, err := fileContent.GetContent()
 	if err != nil {
 		log.Printf("Error decoding file content for %s (branch: %s): %v", path, branch, err)
@@ -176,8 +198,52 @@ func (c *Client) GetFileContent(ctx context.Context, owner, repo, path string, b
 	return content, nil
 }
 
+// GetCommitSHA resolves the commit SHA that a ref (branch, tag or SHA) currently points to.
+// If lastSHA is given and the ref has not moved, GitHub answers 304 Not Modified (which does
+// not count against the rate limit) and lastSHA is returned with changed set to false.
+func (c *Client) GetCommitSHA(ctx context.Context, owner, repo, ref, lastSHA string) (sha string, changed bool, err error) {
+	ctx = waitOnRateLimit(ctx)
+	if ref == "" {
+		ref = "HEAD"
+	}
+
+	sha, _, err = c.Repositories.GetCommitSHA1(ctx, owner, repo, ref, lastSHA)
+	if err != nil {
+		var ghErr *github.ErrorResponse
+		if errors.As(err, &ghErr) && ghErr.Response.StatusCode == http.StatusNotModified {
+			return lastSHA, false, nil
+		}
+		log.Printf("Error resolving commit SHA for %s/%s ref %s: %v", owner, repo, ref, err)
+		return "", false, fmt.Errorf("failed to resolve commit for ref '%s': %w", ref, err)
+	}
+
+	return sha, sha != lastSHA, nil
+}
+
+// GetBlobContent fetches the raw content of a file by its blob SHA.
+// Unlike the Contents API, the Blobs API supports files up to 100 MB.
+func (c *Client) GetBlobContent(ctx context.Context, owner, repo, sha string) (string, error) {
+	ctx = waitOnRateLimit(ctx)
+	if sha == "" {
+		return "", fmt.Errorf("cannot fetch blob without a SHA")
+	}
+
+	data, _, err := c.Git.GetBlobRaw(ctx, owner, repo, sha)
+	if err != nil {
+		log.Printf("Error getting blob %s for %s/%s: %v", sha, owner, repo, err)
+		var ghErr *github.ErrorResponse
+		if errors.As(err, &ghErr) && ghErr.Response.StatusCode == http.StatusNotFound {
+			return "", fmt.Errorf("blob not found: %s", sha)
+		}
+		return "", fmt.Errorf("failed to get blob '%s': %w", sha, err)
+	}
+
+	return string(data), nil
+}
+
 // GetDefaultBranch fetches the default branch name for a given repository.
 func (c *Client) GetDefaultBranch(ctx context.Context, owner, repo string) (string, error) {
+	ctx = waitOnRateLimit(ctx)
 	repoInfo, _, err := c.Client.Repositories.Get(ctx, owner, repo)
 	if err != nil {
 		// Handle common errors like 404 Not Found gracefully
@@ -196,3 +262,78 @@ func (c *Client) GetDefaultBranch(ctx context.Context, owner, repo string) (stri
 
 	return *repoInfo.DefaultBranch, nil
 }
+
+// BranchExists reports whether the repository has a branch with the given name.
+func (c *Client) BranchExists(ctx context.Context, owner, repo, branch string) (bool, error) {
+	ctx = waitOnRateLimit(ctx)
+	// Redirects are not followed: a renamed branch answers 301, but the old name is no
+	// longer a ref that can be synced
+	_, resp, err := c.Repositories.GetBranch(ctx, owner, repo, branch, 0)
+	if err != nil {
+		if resp != nil && (resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusMovedPermanently) {
+			return false, nil
+		}
+		log.Printf("Error getting branch %s of %s/%s: %v", branch, owner, repo, err)
+		return false, fmt.Errorf("failed to get branch '%s' of %s/%s: %w", branch, owner, repo, err)
+	}
+	return true, nil
+}
+
+// TagExists reports whether the repository has a tag with the given name.
+func (c *Client) TagExists(ctx context.Context, owner, repo, tag string) (bool, error) {
+	ctx = waitOnRateLimit(ctx)
+	_, _, err := c.Git.GetRef(ctx, owner, repo, "tags/"+tag)
+	if err != nil {
+		var ghErr *github.ErrorResponse
+		if errors.As(err, &ghErr) && ghErr.Response.StatusCode == http.StatusNotFound {
+			return false, nil
+		}
+		log.Printf("Error getting tag %s of %s/%s: %v", tag, owner, repo, err)
+		return false, fmt.Errorf("failed to get tag '%s' of %s/%s: %w", tag, owner, repo, err)
+	}
+	return true, nil
+}
+
+// ListTagNames returns the names of the repository's tags starting with prefix (all tags
+// if prefix is empty).
+func (c *Client) ListTagNames(ctx context.Context, owner, repo, prefix string) ([]string, error) {
+	ctx = waitOnRateLimit(ctx)
+	opts := &github.ReferenceListOptions{
+		Ref:         "tags/" + prefix,
+		ListOptions: github.ListOptions{PerPage: 100},
+	}
+	var names []string
+	for {
+		refs, resp, err := c.Git.ListMatchingRefs(ctx, owner, repo, opts)
+		if err != nil {
+			log.Printf("Error listing tags of %s/%s: %v", owner, repo, err)
+			return nil, fmt.Errorf("failed to list tags of %s/%s: %w", owner, repo, err)
+		}
+		for _, ref := range refs {
+			names = append(names, strings.TrimPrefix(ref.GetRef(), "refs/tags/"))
+		}
+		if resp.NextPage == 0 {
+			return names, nil
+		}
+		opts.Page = resp.NextPage
+	}
+}
+
+// GetLatestReleaseTag returns the tag name of the repository's latest release, as GitHub
+// defines it: the most recent release that is neither a draft nor a prerelease.
+func (c *Client) GetLatestReleaseTag(ctx context.Context, owner, repo string) (string, error) {
+	ctx = waitOnRateLimit(ctx)
+	release, _, err := c.Repositories.GetLatestRelease(ctx, owner, repo)
+	if err != nil {
+		var ghErr *github.ErrorResponse
+		if errors.As(err, &ghErr) && ghErr.Response.StatusCode == http.StatusNotFound {
+			return "", fmt.Errorf("repository %s/%s has no published release", owner, repo)
+		}
+		log.Printf("Error getting latest release of %s/%s: %v", owner, repo, err)
+		return "", fmt.Errorf("failed to get latest release of %s/%s: %w", owner, repo, err)
+	}
+	if release.GetTagName() == "" {
+		return "", fmt.Errorf("latest release of %s/%s has no tag", owner, repo)
+	}
+	return release.GetTagName(), nil
+}
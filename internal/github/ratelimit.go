@@ -0,0 +1,314 @@
+package github
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"log"
+	"math/rand/v2"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// maxRetries is the number of times a request is retried after a transient failure.
+	maxRetries = 3
+	// baseBackoff is the initial delay for exponential backoff between retries.
+	baseBackoff = time.Second
+	// secondaryLimitBackoff is how long to pause after a secondary rate limit without Retry-After,
+	// as recommended by GitHub.
+	secondaryLimitBackoff = time.Minute
+	// slowdownFraction is the share of the hourly budget below which requests are spaced out
+	// evenly until the limit resets.
+	slowdownFraction = 0.1
+	// maxSlowdownDelay caps the delay added per request while slowing down.
+	maxSlowdownDelay = 30 * time.Second
+)
+
+// RateLimitState is the last known rate limit budget of one GitHub API resource.
+type RateLimitState struct {
+	Resource  string    `json:"resource"` // e.g., core, search, graphql
+	Limit     int       `json:"limit"`
+	Remaining int       `json:"remaining"`
+	Used      int       `json:"used"`
+	Reset     time.Time `json:"reset"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// RateLimitStatus reports the rate limit budgets seen so far and any active pause.
+type RateLimitStatus struct {
+	Resources    []RateLimitState `json:"resources"`
+	BlockedUntil *time.Time       `json:"blocked_until,omitempty"` // Set while honoring Retry-After or a secondary limit
+}
+
+// rateLimitTransport tracks GitHub rate limit headers on every response, paces requests
+// when the budget runs low, and retries rate-limited and transient failures with backoff.
+type rateLimitTransport struct {
+	base http.RoundTripper
+
+	// Clock, sleep and backoff jitter, replaced in tests
+	now    func() time.Time
+	sleep  func(ctx context.Context, d time.Duration) error
+	jitter func(n time.Duration) time.Duration // Returns a random duration in [0, n]
+
+	mu           sync.Mutex
+	states       map[string]RateLimitState
+	blockedUntil time.Time
+}
+
+// newRateLimitTransport wraps base with rate limit tracking and retries.
+func newRateLimitTransport(base http.RoundTripper) *rateLimitTransport {
+	return &rateLimitTransport{
+		base:   base,
+		now:    time.Now,
+		sleep:  sleepContext,
+		jitter: func(n time.Duration) time.Duration { return rand.N(n + 1) },
+		states: make(map[string]RateLimitState),
+	}
+}
+
+type attemptTimeoutKey struct{}
+
+// WithAttemptTimeout returns a context that limits each HTTP attempt of the GitHub requests
+// made with it to d. Unlike a deadline on ctx, the limit does not cover waiting for the rate
+// limit to reset or between retries, so a request can outlast it without failing.
+func WithAttemptTimeout(ctx context.Context, d time.Duration) context.Context {
+	return context.WithValue(ctx, attemptTimeoutKey{}, d)
+}
+
+// cancelOnClose cancels the context of a request attempt once its response body is closed.
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+// Close implements io.Closer.
+func (b *cancelOnClose) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *rateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	attemptTimeout, _ := ctx.Value(attemptTimeoutKey{}).(time.Duration)
+	for attempt := 0; ; attempt++ {
+		if err := t.sleep(ctx, t.delayBeforeRequest()); err != nil {
+			return nil, err
+		}
+
+		attemptReq := req
+		if attempt > 0 {
+			var ok bool
+			if attemptReq, ok = rewindRequest(req); !ok {
+				return nil, errors.New("request body cannot be replayed for retry")
+			}
+		}
+
+		cancelAttempt := context.CancelFunc(func() {})
+		if attemptTimeout > 0 {
+			var attemptCtx context.Context
+			attemptCtx, cancelAttempt = context.WithTimeout(ctx, attemptTimeout)
+			attemptReq = attemptReq.WithContext(attemptCtx)
+		}
+
+		resp, err := t.base.RoundTrip(attemptReq)
+		if err != nil {
+			cancelAttempt()
+			if ctx.Err() != nil || attempt >= maxRetries {
+				return nil, err
+			}
+			wait := t.backoff(attempt)
+			log.Printf("GitHub request %s %s failed (%v), retrying in %s", req.Method, req.URL.Path, err, wait)
+			if err := t.sleep(ctx, wait); err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		resp.Body = &cancelOnClose{ReadCloser: resp.Body, cancel: cancelAttempt}
+		t.recordHeaders(resp.Header)
+
+		wait, retry := t.retryDelay(resp, attempt)
+		if !retry || attempt >= maxRetries || !canRewind(req) {
+			return resp, nil
+		}
+		log.Printf("GitHub request %s %s returned %d, retrying in %s (attempt %d/%d)", req.Method, req.URL.Path, resp.StatusCode, wait.Round(time.Millisecond), attempt+1, maxRetries)
+		// Drain and close the body so the connection can be reused
+		_, _ = io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+		if err := t.sleep(ctx, wait); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// Status returns a snapshot of the known rate limit budgets.
+func (t *rateLimitTransport) Status() RateLimitStatus {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	status := RateLimitStatus{Resources: make([]RateLimitState, 0, len(t.states))}
+	for _, state := range t.states {
+		status.Resources = append(status.Resources, state)
+	}
+	sort.Slice(status.Resources, func(i, j int) bool {
+		return status.Resources[i].Resource < status.Resources[j].Resource
+	})
+	if t.now().Before(t.blockedUntil) {
+		blockedUntil := t.blockedUntil
+		status.BlockedUntil = &blockedUntil
+	}
+	return status
+}
+
+// delayBeforeRequest returns how long to wait before sending the next request:
+// until an active pause ends, until the reset if the core budget is exhausted,
+// or a share of the time to reset when the budget is running low.
+func (t *rateLimitTransport) delayBeforeRequest() time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := t.now()
+	if now.Before(t.blockedUntil) {
+		return t.blockedUntil.Sub(now)
+	}
+
+	core, ok := t.states["core"]
+	if !ok || core.Limit == 0 || !now.Before(core.Reset) {
+		return 0
+	}
+	untilReset := core.Reset.Sub(now)
+	if core.Remaining <= 0 {
+		return untilReset
+	}
+	if float64(core.Remaining) < float64(core.Limit)*slowdownFraction {
+		// Spread the remaining budget evenly over the time left; concurrent workers share it
+		return min(untilReset/time.Duration(core.Remaining), maxSlowdownDelay)
+	}
+	return 0
+}
+
+// recordHeaders updates the tracked budget from the X-RateLimit-* headers of a response.
+func (t *rateLimitTransport) recordHeaders(header http.Header) {
+	limit, err := strconv.Atoi(header.Get("X-RateLimit-Limit"))
+	if err != nil {
+		return // Not a rate-limited endpoint (or not a GitHub API response)
+	}
+	remaining, _ := strconv.Atoi(header.Get("X-RateLimit-Remaining"))
+	used, _ := strconv.Atoi(header.Get("X-RateLimit-Used"))
+	resetUnix, _ := strconv.ParseInt(header.Get("X-RateLimit-Reset"), 10, 64)
+	resource := header.Get("X-RateLimit-Resource")
+	if resource == "" {
+		resource = "core"
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.states[resource] = RateLimitState{
+		Resource:  resource,
+		Limit:     limit,
+		Remaining: remaining,
+		Used:      used,
+		Reset:     time.Unix(resetUnix, 0),
+		UpdatedAt: t.now(),
+	}
+}
+
+// retryDelay decides whether a response should be retried and after how long.
+// Rate-limited responses honor Retry-After or the reset time and pause all requests
+// until then, as does a secondary rate limit. 5xx responses are retried with jittered exponential backoff.
+func (t *rateLimitTransport) retryDelay(resp *http.Response, attempt int) (time.Duration, bool) {
+	switch {
+	case resp.StatusCode == http.StatusForbidden || resp.StatusCode == http.StatusTooManyRequests:
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+			return t.block(time.Duration(seconds) * time.Second), true
+		}
+		if resp.Header.Get("X-RateLimit-Remaining") == "0" {
+			resetUnix, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64)
+			if err == nil {
+				// Concurrent requests would fail the same way until the reset
+				return t.block(time.Unix(resetUnix, 0).Sub(t.now())), true
+			}
+		}
+		if isSecondaryRateLimit(resp) {
+			return t.block(secondaryLimitBackoff), true
+		}
+		return 0, false // A genuine permission error
+	case resp.StatusCode >= 500 && resp.StatusCode != http.StatusNotImplemented:
+		return t.backoff(attempt), true
+	default:
+		return 0, false
+	}
+}
+
+// block pauses all requests for d and returns d.
+func (t *rateLimitTransport) block(d time.Duration) time.Duration {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if until := t.now().Add(d); until.After(t.blockedUntil) {
+		t.blockedUntil = until
+	}
+	return d
+}
+
+// isSecondaryRateLimit reports whether a 403/429 response is a secondary (abuse) rate limit.
+// The body is read and replaced so the caller can still decode it.
+func isSecondaryRateLimit(resp *http.Response) bool {
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return false
+	}
+	message := strings.ToLower(string(body))
+	return strings.Contains(message, "secondary rate limit") || strings.Contains(message, "abuse")
+}
+
+// backoff returns a jittered exponential delay for the given retry attempt.
+func (t *rateLimitTransport) backoff(attempt int) time.Duration {
+	delay := baseBackoff << attempt
+	return delay/2 + t.jitter(delay/2)
+}
+
+// canRewind reports whether a request can be sent again.
+func canRewind(req *http.Request) bool {
+	return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+}
+
+// rewindRequest returns a copy of req with a fresh body for a retry.
+func rewindRequest(req *http.Request) (*http.Request, bool) {
+	if !canRewind(req) {
+		return nil, false
+	}
+	clone := req.Clone(req.Context())
+	if req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			return nil, false
+		}
+		clone.Body = body
+	}
+	return clone, true
+}
+
+// sleepContext waits for d or until ctx is done, whichever comes first.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,387 @@
+package github
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-github/v62/github"
+)
+
+// fakeClock replaces the clock and sleep of a rateLimitTransport. Sleeping records the
+// duration and advances the clock instead of waiting. Like sleepContext, it fails if the
+// context would expire first.
+type fakeClock struct {
+	mu     sync.Mutex
+	now    time.Time
+	sleeps []time.Duration
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Sleep(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return nil
+	}
+	if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < d {
+		return context.DeadlineExceeded
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sleeps = append(c.sleeps, d)
+	c.now = c.now.Add(d)
+	return nil
+}
+
+func (c *fakeClock) Sleeps() []time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return slices.Clone(c.sleeps)
+}
+
+// scriptedServer answers the n-th request with the n-th handler, repeating the last one.
+func scriptedServer(t *testing.T, handlers ...http.HandlerFunc) (*httptest.Server, func() int) {
+	t.Helper()
+	var mu sync.Mutex
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		i := min(requests, len(handlers)-1)
+		requests++
+		mu.Unlock()
+		handlers[i](w, r)
+	}))
+	t.Cleanup(server.Close)
+	return server, func() int {
+		mu.Lock()
+		defer mu.Unlock()
+		return requests
+	}
+}
+
+func respond(status int, headers map[string]string, body string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		for key, value := range headers {
+			w.Header().Set(key, value)
+		}
+		w.WriteHeader(status)
+		io.WriteString(w, body)
+	}
+}
+
+// testEpoch is the time fake clocks start at.
+var testEpoch = time.Unix(1_700_000_000, 0)
+
+// newTestTransport returns a transport with a fake clock and jitter that always picks
+// the longest backoff.
+func newTestTransport() (*rateLimitTransport, *fakeClock) {
+	clock := &fakeClock{now: testEpoch}
+	transport := newRateLimitTransport(http.DefaultTransport)
+	transport.now = clock.Now
+	transport.sleep = clock.Sleep
+	transport.jitter = func(n time.Duration) time.Duration { return n }
+	return transport, clock
+}
+
+func get(t *testing.T, transport *rateLimitTransport, url string) (*http.Response, string) {
+	t.Helper()
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := transport.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("RoundTrip: %v", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp, string(body)
+}
+
+func TestRateLimitTransportRetryAfter(t *testing.T) {
+	transport, clock := newTestTransport()
+	server, requests := scriptedServer(t,
+		respond(http.StatusTooManyRequests, map[string]string{"Retry-After": "5"}, `{"message":"slow down"}`),
+		respond(http.StatusOK, nil, "ok"),
+	)
+
+	resp, body := get(t, transport, server.URL)
+	if resp.StatusCode != http.StatusOK || body != "ok" {
+		t.Fatalf("got %d %q, want 200 \"ok\"", resp.StatusCode, body)
+	}
+	if got := requests(); got != 2 {
+		t.Errorf("requests = %d, want 2", got)
+	}
+	if got, want := clock.Sleeps(), []time.Duration{5 * time.Second}; !slices.Equal(got, want) {
+		t.Errorf("sleeps = %v, want %v", got, want)
+	}
+}
+
+func TestRateLimitTransportSecondaryLimit(t *testing.T) {
+	transport, clock := newTestTransport()
+	server, requests := scriptedServer(t,
+		respond(http.StatusForbidden, nil, `{"message":"You have exceeded a secondary rate limit."}`),
+		respond(http.StatusOK, nil, "ok"),
+	)
+
+	resp, _ := get(t, transport, server.URL)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want 200", resp.StatusCode)
+	}
+	if got := requests(); got != 2 {
+		t.Errorf("requests = %d, want 2", got)
+	}
+	if got, want := clock.Sleeps(), []time.Duration{secondaryLimitBackoff}; !slices.Equal(got, want) {
+		t.Errorf("sleeps = %v, want %v", got, want)
+	}
+}
+
+func TestRateLimitTransportPermissionErrorNotRetried(t *testing.T) {
+	transport, clock := newTestTransport()
+	const message = `{"message":"Resource not accessible by integration"}`
+	server, requests := scriptedServer(t, respond(http.StatusForbidden, nil, message))
+
+	resp, body := get(t, transport, server.URL)
+	if resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("status = %d, want 403", resp.StatusCode)
+	}
+	if body != message {
+		t.Errorf("body = %q, want %q", body, message)
+	}
+	if got := requests(); got != 1 {
+		t.Errorf("requests = %d, want 1", got)
+	}
+	if got := clock.Sleeps(); len(got) != 0 {
+		t.Errorf("sleeps = %v, want none", got)
+	}
+}
+
+func TestRateLimitTransportServerErrorBackoff(t *testing.T) {
+	transport, clock := newTestTransport()
+	server, requests := scriptedServer(t,
+		respond(http.StatusBadGateway, nil, ""),
+		respond(http.StatusServiceUnavailable, nil, ""),
+		respond(http.StatusOK, nil, "ok"),
+	)
+
+	req, err := http.NewRequest(http.MethodPost, server.URL, strings.NewReader("payload"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := transport.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("RoundTrip: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want 200", resp.StatusCode)
+	}
+	if got := requests(); got != 3 {
+		t.Errorf("requests = %d, want 3", got)
+	}
+	if got, want := clock.Sleeps(), []time.Duration{baseBackoff, 2 * baseBackoff}; !slices.Equal(got, want) {
+		t.Errorf("sleeps = %v, want %v", got, want)
+	}
+}
+
+func TestRateLimitTransportServerErrorGivesUp(t *testing.T) {
+	transport, clock := newTestTransport()
+	server, requests := scriptedServer(t, respond(http.StatusInternalServerError, nil, "boom"))
+
+	resp, body := get(t, transport, server.URL)
+	if resp.StatusCode != http.StatusInternalServerError || body != "boom" {
+		t.Fatalf("got %d %q, want 500 \"boom\"", resp.StatusCode, body)
+	}
+	if got, want := requests(), maxRetries+1; got != want {
+		t.Errorf("requests = %d, want %d", got, want)
+	}
+	if got := len(clock.Sleeps()); got != maxRetries {
+		t.Errorf("sleeps = %d, want %d", got, maxRetries)
+	}
+}
+
+func TestRateLimitTransportExhaustedBudget(t *testing.T) {
+	transport, clock := newTestTransport()
+	reset := clock.Now().Add(2 * time.Minute)
+	headers := map[string]string{
+		"X-RateLimit-Limit":     "5000",
+		"X-RateLimit-Remaining": "0",
+		"X-RateLimit-Used":      "5000",
+		"X-RateLimit-Reset":     strconv.FormatInt(reset.Unix(), 10),
+	}
+	server, requests := scriptedServer(t,
+		respond(http.StatusOK, headers, "last"),
+		respond(http.StatusOK, nil, "ok"),
+	)
+
+	get(t, transport, server.URL)
+	if got := clock.Sleeps(); len(got) != 0 {
+		t.Fatalf("first request slept %v, want no wait", got)
+	}
+	status := transport.Status()
+	if len(status.Resources) != 1 || status.Resources[0].Resource != "core" || status.Resources[0].Remaining != 0 {
+		t.Errorf("status = %+v, want an exhausted core budget", status)
+	}
+
+	// The next request waits for the reset instead of failing
+	get(t, transport, server.URL)
+	if got, want := clock.Sleeps(), []time.Duration{2 * time.Minute}; !slices.Equal(got, want) {
+		t.Errorf("sleeps = %v, want %v", got, want)
+	}
+	if got := requests(); got != 2 {
+		t.Errorf("requests = %d, want 2", got)
+	}
+}
+
+func TestRateLimitTransportExhaustedBudgetRetry(t *testing.T) {
+	transport, clock := newTestTransport()
+	reset := clock.Now().Add(30 * time.Second)
+	server, requests := scriptedServer(t,
+		respond(http.StatusForbidden, map[string]string{
+			"X-RateLimit-Limit":     "5000",
+			"X-RateLimit-Remaining": "0",
+			"X-RateLimit-Reset":     strconv.FormatInt(reset.Unix(), 10),
+		}, `{"message":"API rate limit exceeded"}`),
+		respond(http.StatusOK, nil, "ok"),
+	)
+
+	resp, _ := get(t, transport, server.URL)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status = %d, want 200", resp.StatusCode)
+	}
+	if got := requests(); got != 2 {
+		t.Errorf("requests = %d, want 2", got)
+	}
+	if got, want := clock.Sleeps(), []time.Duration{30 * time.Second}; !slices.Equal(got, want) {
+		t.Errorf("sleeps = %v, want %v", got, want)
+	}
+}
+
+func TestRateLimitTransportSlowdown(t *testing.T) {
+	transport, clock := newTestTransport()
+	reset := clock.Now().Add(100 * time.Second)
+	server, _ := scriptedServer(t, respond(http.StatusOK, map[string]string{
+		"X-RateLimit-Limit":     "5000",
+		"X-RateLimit-Remaining": "10",
+		"X-RateLimit-Reset":     strconv.FormatInt(reset.Unix(), 10),
+	}, "ok"))
+
+	get(t, transport, server.URL)
+	get(t, transport, server.URL)
+	// 10 requests left for 100 seconds: one every 10 seconds
+	if got, want := clock.Sleeps(), []time.Duration{10 * time.Second}; !slices.Equal(got, want) {
+		t.Errorf("sleeps = %v, want %v", got, want)
+	}
+}
+
+// newTestClient returns a Client sending its requests to server through a transport with a
+// fake clock.
+func newTestClient(t *testing.T, server *httptest.Server) (*Client, *fakeClock) {
+	t.Helper()
+	httpClient, rateLimits := newHTTPClient(nil)
+	clock := &fakeClock{now: testEpoch}
+	rateLimits.now = clock.Now
+	rateLimits.sleep = clock.Sleep
+	rateLimits.jitter = func(n time.Duration) time.Duration { return n }
+
+	client := github.NewClient(httpClient)
+	baseURL, err := url.Parse(server.URL + "/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	client.BaseURL = baseURL
+	return &Client{Client: client, rateLimits: rateLimits}, clock
+}
+
+func TestGetFileContentWaitsOutsideAttemptTimeout(t *testing.T) {
+	const file = `{"type":"file","encoding":"base64","content":"aGVsbG8=","path":"docs/index.md"}`
+	handlers := []http.HandlerFunc{
+		respond(http.StatusForbidden, map[string]string{
+			"X-RateLimit-Limit":     "5000",
+			"X-RateLimit-Remaining": "0",
+			"X-RateLimit-Reset":     strconv.FormatInt(testEpoch.Add(2*time.Minute).Unix(), 10),
+		}, `{"message":"API rate limit exceeded"}`),
+		respond(http.StatusOK, nil, file),
+	}
+
+	t.Run("attempt timeout", func(t *testing.T) {
+		server, _ := scriptedServer(t, handlers...)
+		client, clock := newTestClient(t, server)
+
+		ctx := WithAttemptTimeout(context.Background(), 30*time.Second)
+		content, err := client.GetFileContent(ctx, "owner", "repo", "docs/index.md", "abc123")
+		if err != nil {
+			t.Fatalf("GetFileContent() error: %v", err)
+		}
+		if content != "hello" {
+			t.Errorf("content = %q, want %q", content, "hello")
+		}
+		if got, want := clock.Sleeps(), []time.Duration{2 * time.Minute}; !slices.Equal(got, want) {
+			t.Errorf("sleeps = %v, want %v", got, want)
+		}
+	})
+
+	t.Run("deadline", func(t *testing.T) {
+		// A deadline on the whole fetch cannot cover the wait for the reset
+		server, _ := scriptedServer(t, handlers...)
+		client, _ := newTestClient(t, server)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		if _, err := client.GetFileContent(ctx, "owner", "repo", "docs/index.md", "abc123"); !errors.Is(err, context.DeadlineExceeded) {
+			t.Errorf("GetFileContent() error = %v, want %v", err, context.DeadlineExceeded)
+		}
+	})
+}
+
+func TestRateLimitTransportAttemptTimeout(t *testing.T) {
+	transport, clock := newTestTransport()
+	release := make(chan struct{})
+	defer close(release)
+	server, requests := scriptedServer(t,
+		func(w http.ResponseWriter, r *http.Request) {
+			select {
+			case <-r.Context().Done():
+			case <-release:
+			}
+		},
+		respond(http.StatusOK, nil, "ok"),
+	)
+
+	ctx := WithAttemptTimeout(context.Background(), 50*time.Millisecond)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := transport.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("RoundTrip: %v", err)
+	}
+	defer resp.Body.Close()
+	// The body is still readable: the attempt is only cancelled once it is closed
+	if body, err := io.ReadAll(resp.Body); err != nil || string(body) != "ok" {
+		t.Errorf("body = %q, %v; want \"ok\"", body, err)
+	}
+	if got := requests(); got != 2 {
+		t.Errorf("requests = %d, want 2", got)
+	}
+	if got, want := clock.Sleeps(), []time.Duration{baseBackoff}; !slices.Equal(got, want) {
+		t.Errorf("sleeps = %v, want %v", got, want)
+	}
+}
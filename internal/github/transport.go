@@ -0,0 +1,56 @@
+package github
+
+import (
+	"context"
+	"net/http"
+	"sync/atomic"
+)
+
+// APICallCounter counts the GitHub API requests made with a given context.
+type APICallCounter struct {
+	count atomic.Int64
+}
+
+// Count returns the number of API requests counted so far.
+func (c *APICallCounter) Count() int {
+	return int(c.count.Load())
+}
+
+type apiCallCounterKey struct{}
+
+// WithAPICallCounter returns a context that counts every GitHub API request made with it.
+// Requests made with a derived context are counted as well.
+func WithAPICallCounter(ctx context.Context) (context.Context, *APICallCounter) {
+	counter := &APICallCounter{}
+	return context.WithValue(ctx, apiCallCounterKey{}, counter), counter
+}
+
+// countingTransport increments the APICallCounter attached to a request's context, if any.
+type countingTransport struct {
+	base http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *countingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if counter, ok := req.Context().Value(apiCallCounterKey{}).(*APICallCounter); ok {
+		counter.count.Add(1)
+	}
+	return t.base.RoundTrip(req)
+}
+
+// newHTTPClient wraps the transport of httpClient (or a default client if nil) so that
+// requests are rate limited and retried, and every attempt is counted per context.
+// It returns the wrapped client and its rate limit transport for status reporting.
+func newHTTPClient(httpClient *http.Client) (*http.Client, *rateLimitTransport) {
+	if httpClient == nil {
+		httpClient = &http.Client{}
+	}
+	base := httpClient.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	rateLimits := newRateLimitTransport(&countingTransport{base: base})
+	wrapped := *httpClient
+	wrapped.Transport = rateLimits
+	return &wrapped, rateLimits
+}
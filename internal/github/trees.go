@@ -0,0 +1,170 @@
+package github
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/google/go-github/v62/github"
+)
+
+// Trees resolves paths within the tree of one commit (or ref) with the Git Trees API.
+// The tree of each directory is fetched at most once, so the lookups of a sync share
+// their API calls. A Trees is not safe for concurrent use.
+type Trees struct {
+	client *Client
+	owner  string
+	repo   string
+	ref    string
+	dirs   map[string]*github.Tree // Non-recursive trees by directory path; "" is the root
+}
+
+// NewTrees returns a Trees for the given ref (HEAD if empty). Pass a commit SHA rather than
+// a branch so that all lookups see the same version.
+func (c *Client) NewTrees(owner, repo, ref string) *Trees {
+	if ref == "" {
+		ref = "HEAD"
+	}
+	return &Trees{client: c, owner: owner, repo: repo, ref: ref, dirs: make(map[string]*github.Tree)}
+}
+
+// cleanTreePath trims slashes from path and maps "." to the root.
+func cleanTreePath(path string) string {
+	path = strings.Trim(path, "/")
+	if path == "." {
+		return ""
+	}
+	return path
+}
+
+// dir returns the non-recursive tree of the directory at dirPath, whose tree SHA is sha
+// (the ref for the root), fetching it on first use.
+func (t *Trees) dir(ctx context.Context, dirPath, sha string) (*github.Tree, error) {
+	if tree, ok := t.dirs[dirPath]; ok {
+		return tree, nil
+	}
+	tree, _, err := t.client.Git.GetTree(ctx, t.owner, t.repo, sha, false)
+	if err != nil {
+		return nil, err
+	}
+	t.dirs[dirPath] = tree
+	return tree, nil
+}
+
+// entry walks the tree segment by segment and returns the entry at path.
+// It returns nil (and no error) if any segment of the path does not exist.
+func (t *Trees) entry(ctx context.Context, path string) (*github.TreeEntry, error) {
+	currentSHA := t.ref
+	dirPath := ""
+	segments := strings.Split(path, "/")
+	var found *github.TreeEntry
+	for i, segment := range segments {
+		tree, err := t.dir(ctx, dirPath, currentSHA)
+		if err != nil {
+			var ghErr *github.ErrorResponse
+			if errors.As(err, &ghErr) && ghErr.Response.StatusCode == http.StatusNotFound {
+				return nil, nil
+			}
+			log.Printf("Error resolving path %s in %s/%s (ref: %s): %v", path, t.owner, t.repo, t.ref, err)
+			return nil, fmt.Errorf("failed to resolve path '%s' (ref: %s): %w", path, t.ref, err)
+		}
+
+		found = nil
+		for _, entry := range tree.Entries {
+			if entry.GetPath() == segment {
+				found = entry
+				break
+			}
+		}
+		if found == nil {
+			return nil, nil
+		}
+		if i < len(segments)-1 {
+			if found.GetType() != "tree" {
+				return nil, nil // An intermediate segment is not a directory
+			}
+			currentSHA = found.GetSHA()
+			dirPath = joinTreePath(dirPath, segment)
+		}
+	}
+	return found, nil
+}
+
+// SHA returns the SHA of the tree (or blob, if the path is a file) at path. An empty path
+// returns the root tree SHA. It returns an empty string if the path does not exist.
+func (t *Trees) SHA(ctx context.Context, path string) (string, error) {
+	ctx = waitOnRateLimit(ctx)
+	path = cleanTreePath(path)
+
+	if path == "" {
+		tree, err := t.dir(ctx, "", t.ref)
+		if err != nil {
+			log.Printf("Error getting root tree for %s/%s (ref: %s): %v", t.owner, t.repo, t.ref, err)
+			return "", fmt.Errorf("failed to get root tree (ref: %s): %w", t.ref, err)
+		}
+		return tree.GetSHA(), nil
+	}
+
+	entry, err := t.entry(ctx, path)
+	if err != nil || entry == nil {
+		return "", err
+	}
+	return entry.GetSHA(), nil
+}
+
+// ListFiles lists all files under path. It resolves the tree of the path and fetches it
+// recursively in a single call, falling back to walking subtrees individually when GitHub
+// reports the listing as truncated. It returns a flat list of FileInfo for files only.
+func (t *Trees) ListFiles(ctx context.Context, path string) ([]FileInfo, error) {
+	ctx = waitOnRateLimit(ctx)
+	path = cleanTreePath(path)
+
+	// Resolve the tree SHA of the requested path, one level at a time
+	treeSHA := t.ref
+	if path != "" {
+		entry, err := t.entry(ctx, path)
+		if err != nil {
+			return nil, err
+		}
+		if entry == nil {
+			log.Printf("Warning: Path not found in repo %s/%s (ref: %s): %s", t.owner, t.repo, t.ref, path)
+			return []FileInfo{}, nil // Treat a missing path as empty, like a 404 from the Contents API
+		}
+		if entry.GetType() == "blob" {
+			// The path points directly at a file
+			return []FileInfo{{Path: path, SHA: entry.GetSHA(), Size: entry.GetSize()}}, nil
+		}
+		if entry.GetType() != "tree" {
+			log.Printf("Warning: Path %s in repo %s/%s is a %s, skipping.", path, t.owner, t.repo, entry.GetType())
+			return []FileInfo{}, nil
+		}
+		treeSHA = entry.GetSHA()
+	}
+
+	tree, _, err := t.client.Git.GetTree(ctx, t.owner, t.repo, treeSHA, true)
+	if err != nil {
+		var ghErr *github.ErrorResponse
+		if errors.As(err, &ghErr) && ghErr.Response.StatusCode == http.StatusNotFound {
+			log.Printf("Warning: Tree not found in repo %s/%s (ref: %s): %s", t.owner, t.repo, t.ref, path)
+			return []FileInfo{}, nil
+		}
+		log.Printf("Error getting tree for %s/%s path %s (ref: %s): %v", t.owner, t.repo, path, t.ref, err)
+		return nil, fmt.Errorf("failed to get tree for path '%s' (ref: %s): %w", path, t.ref, err)
+	}
+
+	if !tree.GetTruncated() {
+		var allFiles []FileInfo
+		for _, entry := range tree.Entries {
+			if file, ok := treeEntryToFileInfo(entry, path); ok {
+				allFiles = append(allFiles, file)
+			}
+		}
+		return allFiles, nil
+	}
+
+	log.Printf("Tree listing for %s/%s path %s (ref: %s) was truncated, walking subtrees individually", t.owner, t.repo, path, t.ref)
+	return t.client.walkTree(ctx, t.owner, t.repo, treeSHA, path)
+}
@@ -0,0 +1,140 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// treeServer serves the Git trees of a small repository and counts the tree requests.
+//
+//	README.md
+//	.syncdocsignore
+//	docs/index.md
+//	docs/.syncdocsignore
+//	docs/guide/setup.md
+func treeServer(t *testing.T) (*httptest.Server, func() []string) {
+	t.Helper()
+	type entry struct {
+		Path string `json:"path"`
+		Type string `json:"type"`
+		SHA  string `json:"sha"`
+		Mode string `json:"mode"`
+	}
+	blob := func(path, sha string) entry { return entry{Path: path, Type: "blob", SHA: sha, Mode: "100644"} }
+	tree := func(path, sha string) entry { return entry{Path: path, Type: "tree", SHA: sha, Mode: "040000"} }
+	trees := map[string][]entry{
+		"commit": {blob("README.md", "readme"), blob(".syncdocsignore", "rootignore"), tree("docs", "docs")},
+		"docs":   {blob("index.md", "index"), blob(".syncdocsignore", "docsignore"), tree("guide", "guide")},
+		"guide":  {blob("setup.md", "setup")},
+	}
+	recursive := map[string][]entry{
+		"docs": {blob("index.md", "index"), blob(".syncdocsignore", "docsignore"), tree("guide", "guide"), blob("guide/setup.md", "setup")},
+	}
+
+	var mu sync.Mutex
+	var requests []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sha, ok := strings.CutPrefix(r.URL.Path, "/repos/owner/repo/git/trees/")
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		entries := trees[sha]
+		if r.URL.Query().Get("recursive") != "" {
+			sha += " (recursive)"
+			entries = recursive[strings.TrimSuffix(sha, " (recursive)")]
+		}
+		mu.Lock()
+		requests = append(requests, sha)
+		mu.Unlock()
+		if entries == nil {
+			http.NotFound(w, r)
+			return
+		}
+		treeSHA := strings.TrimSuffix(sha, " (recursive)")
+		if treeSHA == "commit" {
+			treeSHA = "root"
+		}
+		json.NewEncoder(w).Encode(map[string]any{"sha": treeSHA, "tree": entries, "truncated": false})
+	}))
+	t.Cleanup(server.Close)
+	return server, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return slices.Clone(requests)
+	}
+}
+
+func TestTreesShareLookups(t *testing.T) {
+	server, requests := treeServer(t)
+	client, _ := newTestClient(t, server)
+	trees := client.NewTrees("owner", "repo", "commit")
+	ctx := context.Background()
+
+	// The lookups of a sync with the single source path "docs": fingerprint, ignore files, listing
+	shas := map[string]string{
+		".syncdocsignore":      "rootignore",
+		"docs":                 "docs",
+		"/docs/":               "docs",
+		"docs/.syncdocsignore": "docsignore",
+		"docs/guide/setup.md":  "setup",
+		"docs/missing.md":      "",
+		"README.md/x":          "",
+		"":                     "root",
+	}
+	for path, want := range shas {
+		got, err := trees.SHA(ctx, path)
+		if err != nil {
+			t.Fatalf("SHA(%q) error: %v", path, err)
+		}
+		if got != want {
+			t.Errorf("SHA(%q) = %q, want %q", path, got, want)
+		}
+	}
+	files, err := trees.ListFiles(ctx, "docs")
+	if err != nil {
+		t.Fatalf("ListFiles() error: %v", err)
+	}
+	var paths []string
+	for _, file := range files {
+		paths = append(paths, file.Path)
+	}
+	if want := []string{"docs/index.md", "docs/.syncdocsignore", "docs/guide/setup.md"}; !slices.Equal(paths, want) {
+		t.Errorf("ListFiles() = %v, want %v", paths, want)
+	}
+
+	// Each directory is fetched once, plus the recursive listing
+	got := requests()
+	slices.Sort(got)
+	if want := []string{"commit", "docs", "docs (recursive)", "guide"}; !slices.Equal(got, want) {
+		t.Errorf("tree requests = %v, want %v", got, want)
+	}
+}
+
+func TestTreesListFile(t *testing.T) {
+	server, requests := treeServer(t)
+	client, _ := newTestClient(t, server)
+	trees := client.NewTrees("owner", "repo", "commit")
+
+	files, err := trees.ListFiles(context.Background(), "docs/index.md")
+	if err != nil {
+		t.Fatalf("ListFiles() error: %v", err)
+	}
+	if len(files) != 1 || files[0].Path != "docs/index.md" || files[0].SHA != "index" {
+		t.Errorf("ListFiles() = %+v, want docs/index.md alone", files)
+	}
+	if got := len(requests()); got != 2 {
+		t.Errorf("%d tree requests, want 2", got)
+	}
+
+	files, err = trees.ListFiles(context.Background(), "nowhere")
+	if err != nil || len(files) != 0 {
+		t.Errorf("ListFiles(missing) = %+v, %v; want no files", files, err)
+	}
+}
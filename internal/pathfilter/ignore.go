@@ -0,0 +1,145 @@
+package pathfilter
+
+import (
+	"path"
+	"strings"
+)
+
+// IgnoreFileName is the name of the ignore file read from source repositories.
+const IgnoreFileName = ".syncdocsignore"
+
+// Ignore excludes files with rules written in gitignore syntax, read from one or more
+// ignore files. Rules of a file apply to paths below the directory that contains it;
+// when files are added in order from the root down, deeper files take precedence.
+type Ignore struct {
+	rules []ignoreRule
+}
+
+// ignoreRule is a single parsed line of an ignore file.
+type ignoreRule struct {
+	base     string   // Directory containing the ignore file; empty for the repository root
+	segments []string // Pattern segments, relative to base
+	negate   bool     // "!" re-includes matching paths
+	dirOnly  bool     // A trailing "/" only matches directories
+}
+
+// Add parses the content of the ignore file at filePath and appends its rules.
+// It returns the rules that were added, as written, for reporting.
+func (ig *Ignore) Add(filePath, content string) []string {
+	base := strings.Trim(path.Dir(filePath), "/")
+	if base == "." {
+		base = ""
+	}
+
+	var added []string
+	for _, line := range strings.Split(content, "\n") {
+		line = strings.TrimRight(line, " \t\r")
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		r := ignoreRule{base: base}
+		pattern := line
+		if strings.HasPrefix(pattern, "!") {
+			r.negate = true
+			pattern = pattern[1:]
+		} else if strings.HasPrefix(pattern, `\`) {
+			pattern = pattern[1:] // Escaped leading "#" or "!"
+		}
+		if strings.HasSuffix(pattern, "/") {
+			r.dirOnly = true
+			pattern = strings.TrimRight(pattern, "/")
+		}
+		if pattern == "" {
+			continue
+		}
+
+		// A pattern with a slash at the start or in the middle is relative to base;
+		// otherwise it matches a name at any depth.
+		if strings.Contains(pattern, "/") {
+			r.segments = strings.Split(strings.TrimPrefix(pattern, "/"), "/")
+		} else {
+			r.segments = []string{"**", pattern}
+		}
+		if !validSegments(r.segments) {
+			continue // Malformed patterns are ignored, like git does
+		}
+
+		ig.rules = append(ig.rules, r)
+		added = append(added, line)
+	}
+	return added
+}
+
+// Len returns the number of rules.
+func (ig *Ignore) Len() int {
+	return len(ig.rules)
+}
+
+// Match reports whether the file at filePath is ignored, either directly or because
+// one of its parent directories is.
+func (ig *Ignore) Match(filePath string) bool {
+	if len(ig.rules) == 0 {
+		return false
+	}
+	parts := strings.Split(strings.Trim(filePath, "/"), "/")
+	for i := 1; i <= len(parts); i++ {
+		isDir := i < len(parts)
+		if ig.matchPath(parts[:i], isDir) {
+			return true // A file in an ignored directory cannot be re-included
+		}
+	}
+	return false
+}
+
+// matchPath applies the rules to a path given as segments; the last matching rule wins.
+func (ig *Ignore) matchPath(parts []string, isDir bool) bool {
+	ignored := false
+	for _, r := range ig.rules {
+		if r.dirOnly && !isDir {
+			continue
+		}
+		rel, ok := relativeTo(parts, r.base)
+		if !ok {
+			continue
+		}
+		if matchSegments(r.segments, rel) {
+			ignored = !r.negate
+		}
+	}
+	return ignored
+}
+
+// relativeTo strips the base directory from path segments. It reports false if the
+// path does not lie strictly below base.
+func relativeTo(parts []string, base string) ([]string, bool) {
+	if base == "" {
+		return parts, true
+	}
+	baseParts := strings.Split(base, "/")
+	if len(parts) <= len(baseParts) {
+		return nil, false
+	}
+	for i, segment := range baseParts {
+		if parts[i] != segment {
+			return nil, false
+		}
+	}
+	return parts[len(baseParts):], true
+}
+
+// validSegments reports whether every segment is "**" or a valid path.Match pattern.
+func validSegments(segments []string) bool {
+	for _, segment := range segments {
+		if segment == "" {
+			return false
+		}
+		if segment == "**" {
+			continue
+		}
+		if _, err := path.Match(segment, ""); err != nil {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,68 @@
+package pathfilter
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestIgnoreMatch(t *testing.T) {
+	type ignoreFile struct {
+		path, content string
+	}
+	tests := []struct {
+		name  string
+		files []ignoreFile
+		path  string
+		want  bool
+	}{
+		{"no rules", nil, "docs/a.md", false},
+		{"name at any depth", []ignoreFile{{".syncdocsignore", "draft.md"}}, "docs/guide/draft.md", true},
+		{"glob name", []ignoreFile{{".syncdocsignore", "*.tmp.md"}}, "docs/a.tmp.md", true},
+		{"anchored pattern", []ignoreFile{{".syncdocsignore", "/draft.md"}}, "docs/draft.md", false},
+		{"anchored pattern at root", []ignoreFile{{".syncdocsignore", "/draft.md"}}, "draft.md", true},
+		{"pattern with a slash is anchored", []ignoreFile{{".syncdocsignore", "docs/internal"}}, "docs/internal/a.md", true},
+		{"pattern with a slash elsewhere", []ignoreFile{{".syncdocsignore", "docs/internal"}}, "other/docs/internal/a.md", false},
+		{"directory rule ignores its files", []ignoreFile{{".syncdocsignore", "internal/"}}, "docs/internal/a.md", true},
+		{"directory rule skips files", []ignoreFile{{".syncdocsignore", "internal/"}}, "docs/internal", false},
+		{"double star", []ignoreFile{{".syncdocsignore", "docs/**/secret.md"}}, "docs/a/b/secret.md", true},
+		{"negation re-includes", []ignoreFile{{".syncdocsignore", "*.md\n!keep.md"}}, "docs/keep.md", false},
+		{"negation before rule loses", []ignoreFile{{".syncdocsignore", "!keep.md\n*.md"}}, "docs/keep.md", true},
+		{"negation cannot re-include below an ignored directory", []ignoreFile{{".syncdocsignore", "drafts/\n!drafts/keep.md"}}, "drafts/keep.md", true},
+		{"comments and blank lines", []ignoreFile{{".syncdocsignore", "# draft.md\n\n"}}, "draft.md", false},
+		{"escaped hash", []ignoreFile{{".syncdocsignore", `\#notes.md`}}, "#notes.md", true},
+		{"trailing spaces and CRLF", []ignoreFile{{".syncdocsignore", "draft.md  \r\n"}}, "draft.md", true},
+		{"case-sensitive like git", []ignoreFile{{".syncdocsignore", "draft.md"}}, "DRAFT.md", false},
+		{"nested file applies below its directory", []ignoreFile{{"docs/.syncdocsignore", "*.txt"}}, "docs/a/b.txt", true},
+		{"nested file does not apply elsewhere", []ignoreFile{{"docs/.syncdocsignore", "*.txt"}}, "src/b.txt", false},
+		{"nested anchored pattern is relative to its directory", []ignoreFile{{"docs/.syncdocsignore", "/a.md"}}, "docs/a.md", true},
+		{
+			"deeper file takes precedence",
+			[]ignoreFile{{".syncdocsignore", "*.md"}, {"docs/.syncdocsignore", "!guide.md"}},
+			"docs/guide.md",
+			false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var ig Ignore
+			for _, file := range tt.files {
+				ig.Add(file.path, file.content)
+			}
+			if got := ig.Match(tt.path); got != tt.want {
+				t.Errorf("Match(%q) = %v, want %v", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIgnoreAdd(t *testing.T) {
+	var ig Ignore
+	added := ig.Add(".syncdocsignore", "# comment\n\ndrafts/\n!keep.md\n[broken\n/\n")
+	want := []string{"drafts/", "!keep.md"}
+	if !slices.Equal(added, want) {
+		t.Errorf("Add returned %q, want %q", added, want)
+	}
+	if ig.Len() != len(want) {
+		t.Errorf("Len = %d, want %d", ig.Len(), len(want))
+	}
+}
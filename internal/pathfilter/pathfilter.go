@@ -0,0 +1,156 @@
+package pathfilter
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+)
+
+// Filter selects repository files with an ordered list of glob patterns.
+//
+// Each pattern is matched against the full path of a file in the repository (no leading
+// slash). A pattern prefixed with "!" excludes the files it matches. When several patterns
+// match a file, the last one wins; files matched by no pattern are not selected.
+//
+// Besides the wildcards of path.Match, which never cross a "/", a path segment of "**"
+// matches any number of directories, including none: "docs/**/*.md" matches both
+// "docs/a.md" and "docs/guide/b.md", and "docs/api/generated/**" everything below it.
+//
+// Matching ignores case, as the extension lists that patterns replace did: "**/*.md"
+// selects "README.MD" too.
+type Filter struct {
+	rules []rule
+}
+
+// rule is a single parsed pattern.
+type rule struct {
+	segments []string
+	exclude  bool
+}
+
+// New parses and validates patterns. At least one include pattern is required.
+func New(patterns []string) (*Filter, error) {
+	if len(patterns) == 0 {
+		return nil, errors.New("at least one pattern is required")
+	}
+
+	f := &Filter{rules: make([]rule, 0, len(patterns))}
+	hasInclude := false
+	for _, pattern := range patterns {
+		r, err := parse(pattern)
+		if err != nil {
+			return nil, err
+		}
+		for i, segment := range r.segments {
+			r.segments[i] = strings.ToLower(segment)
+		}
+		hasInclude = hasInclude || !r.exclude
+		f.rules = append(f.rules, r)
+	}
+	if !hasInclude {
+		return nil, errors.New("at least one include pattern is required; a '!' pattern only excludes files")
+	}
+	return f, nil
+}
+
+// Normalize trims the patterns, drops empty ones and validates the result.
+func Normalize(patterns []string) ([]string, error) {
+	normalized := make([]string, 0, len(patterns))
+	for _, pattern := range patterns {
+		if pattern = strings.TrimSpace(pattern); pattern != "" {
+			normalized = append(normalized, pattern)
+		}
+	}
+	if _, err := New(normalized); err != nil {
+		return nil, err
+	}
+	return normalized, nil
+}
+
+// ExtensionPatterns converts a comma-separated extension list (e.g., "md, .MDX") into the
+// equivalent include patterns (e.g., "**/*.md", "**/*.mdx"). The schema migrates stored
+// extension lists the same way.
+func ExtensionPatterns(extensions string) []string {
+	var patterns []string
+	for _, ext := range strings.Split(extensions, ",") {
+		ext = strings.TrimLeft(strings.ToLower(strings.TrimSpace(ext)), ".")
+		if ext != "" {
+			patterns = append(patterns, "**/*."+ext)
+		}
+	}
+	return patterns
+}
+
+// Match reports whether the file at filePath is selected.
+func (f *Filter) Match(filePath string) bool {
+	segments := strings.Split(strings.ToLower(strings.Trim(filePath, "/")), "/")
+	selected := false
+	for _, r := range f.rules {
+		if matchSegments(r.segments, segments) {
+			selected = !r.exclude
+		}
+	}
+	return selected
+}
+
+// parse validates a pattern and splits it into path segments.
+func parse(pattern string) (rule, error) {
+	var r rule
+	trimmed := strings.TrimSpace(pattern)
+	if strings.HasPrefix(trimmed, "!") {
+		r.exclude = true
+		trimmed = trimmed[1:]
+	}
+	trimmed = strings.Trim(trimmed, "/")
+	if trimmed == "" {
+		return r, fmt.Errorf("invalid pattern '%s': pattern is empty", pattern)
+	}
+
+	r.segments = strings.Split(trimmed, "/")
+	for _, segment := range r.segments {
+		if segment == "" {
+			return r, fmt.Errorf("invalid pattern '%s': empty path segment", pattern)
+		}
+		if segment == "**" {
+			continue
+		}
+		if strings.Contains(segment, "**") {
+			return r, fmt.Errorf("invalid pattern '%s': '**' must be a whole path segment", pattern)
+		}
+		if _, err := path.Match(segment, ""); err != nil {
+			return r, fmt.Errorf("invalid pattern '%s': %w", pattern, err)
+		}
+	}
+	return r, nil
+}
+
+// matchSegments matches path segments against pattern segments, where a "**" pattern
+// segment matches zero or more path segments.
+func matchSegments(pattern, segments []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			// Collapse consecutive "**" and try every possible number of skipped segments
+			for len(pattern) > 0 && pattern[0] == "**" {
+				pattern = pattern[1:]
+			}
+			if len(pattern) == 0 {
+				return true
+			}
+			for i := 0; i <= len(segments); i++ {
+				if matchSegments(pattern, segments[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(segments) == 0 {
+			return false
+		}
+		if ok, _ := path.Match(pattern[0], segments[0]); !ok {
+			return false
+		}
+		pattern, segments = pattern[1:], segments[1:]
+	}
+	return len(segments) == 0
+}
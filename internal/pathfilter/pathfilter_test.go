@@ -0,0 +1,101 @@
+package pathfilter
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestFilterMatch(t *testing.T) {
+	tests := []struct {
+		name     string
+		patterns []string
+		path     string
+		want     bool
+	}{
+		{"extension at root", []string{"**/*.md"}, "README.md", true},
+		{"extension nested", []string{"**/*.md"}, "docs/guide/intro.md", true},
+		{"other extension", []string{"**/*.md"}, "docs/guide/intro.txt", false},
+		{"double star matches no directory", []string{"docs/**/*.md"}, "docs/a.md", true},
+		{"double star matches several directories", []string{"docs/**/*.md"}, "docs/a/b/c.md", true},
+		{"outside the prefix", []string{"docs/**/*.md"}, "src/a.md", false},
+		{"trailing double star", []string{"docs/api/**"}, "docs/api/generated/x.json", true},
+		{"consecutive double stars", []string{"**/**/*.md"}, "a.md", true},
+		{"single star does not cross directories", []string{"docs/*.md"}, "docs/guide/a.md", false},
+		{"question mark", []string{"docs/?.md"}, "docs/a.md", true},
+		{"character class", []string{"v[12]/*.md"}, "v2/a.md", true},
+		{"leading and trailing slashes", []string{"/docs/*.md/"}, "/docs/a.md", true},
+		{"exclude after include", []string{"**/*.md", "!docs/drafts/**"}, "docs/drafts/a.md", false},
+		{"include after exclude wins", []string{"**/*.md", "!docs/drafts/**", "docs/drafts/keep.md"}, "docs/drafts/keep.md", true},
+		{"exclude before include loses", []string{"!docs/drafts/**", "**/*.md"}, "docs/drafts/a.md", true},
+		{"exclude leaves others", []string{"**/*.md", "!docs/drafts/**"}, "docs/a.md", true},
+		{"upper-case file", []string{"**/*.md"}, "README.MD", true},
+		{"upper-case pattern", []string{"Docs/**/*.MD"}, "docs/a.md", true},
+		{"case-insensitive exclude", []string{"**/*.md", "!CHANGELOG.md"}, "changelog.MD", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := New(tt.patterns)
+			if err != nil {
+				t.Fatalf("New(%q) returned error: %v", tt.patterns, err)
+			}
+			if got := f.Match(tt.path); got != tt.want {
+				t.Errorf("Match(%q) with %q = %v, want %v", tt.path, tt.patterns, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		patterns []string
+	}{
+		{"no patterns", nil},
+		{"only excludes", []string{"!docs/**"}},
+		{"empty pattern", []string{"**/*.md", "  "}},
+		{"empty segment", []string{"docs//*.md"}},
+		{"double star inside a segment", []string{"docs/a**/*.md"}},
+		{"malformed class", []string{"docs/[a.md"}},
+		{"bare exclamation mark", []string{"**/*.md", "!"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := New(tt.patterns); err == nil {
+				t.Errorf("New(%q) succeeded, want an error", tt.patterns)
+			}
+		})
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	got, err := Normalize([]string{" **/*.md ", "", "!docs/drafts/**"})
+	if err != nil {
+		t.Fatalf("Normalize returned error: %v", err)
+	}
+	want := []string{"**/*.md", "!docs/drafts/**"}
+	if !slices.Equal(got, want) {
+		t.Errorf("Normalize = %q, want %q", got, want)
+	}
+
+	if _, err := Normalize([]string{"", " "}); err == nil {
+		t.Error("Normalize of blank patterns succeeded, want an error")
+	}
+}
+
+func TestExtensionPatterns(t *testing.T) {
+	tests := []struct {
+		extensions string
+		want       []string
+	}{
+		{"md,mdx", []string{"**/*.md", "**/*.mdx"}},
+		{" md , .MDX ", []string{"**/*.md", "**/*.mdx"}},
+		{"..txt", []string{"**/*.txt"}},
+		{"md,,", []string{"**/*.md"}},
+		{"", nil},
+	}
+	for _, tt := range tests {
+		if got := ExtensionPatterns(tt.extensions); !slices.Equal(got, tt.want) {
+			t.Errorf("ExtensionPatterns(%q) = %q, want %q", tt.extensions, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package semver
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Version is a parsed semantic version. Build metadata is dropped, as it does not
+// take part in precedence.
+type Version struct {
+	Major, Minor, Patch int
+	Prerelease          string // e.g., "rc.1"; empty for a release
+}
+
+// Parse reads a version such as "1.2.3", "1.2.3-rc.1" or "1.2.3+build". Missing minor and
+// patch numbers count as zero ("1.2" is 1.2.0). It reports false if s is not a version.
+func Parse(s string) (Version, bool) {
+	var v Version
+	s, _, _ = strings.Cut(s, "+")
+	s, v.Prerelease, _ = strings.Cut(s, "-")
+
+	parts := strings.Split(s, ".")
+	if len(parts) > 3 {
+		return Version{}, false
+	}
+	numbers := [3]int{}
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 || part == "" || (len(part) > 1 && part[0] == '0') {
+			return Version{}, false
+		}
+		numbers[i] = n
+	}
+	v.Major, v.Minor, v.Patch = numbers[0], numbers[1], numbers[2]
+	return v, true
+}
+
+// ParseTag reads the version in the last path segment of a tag, skipping its prefix. A
+// number right after a "v" is preferred, then one after "-" or "_" (or at the start), then
+// any other number: "sdk2/v1.2.3" and "sdk2-v1.2.3" are 1.2.3, "release-1.4" is 1.4.0 and
+// "go1.21" is 1.21.0.
+func ParseTag(tag string) (Version, bool) {
+	if i := strings.LastIndex(tag, "/"); i >= 0 {
+		tag = tag[i+1:]
+	}
+	for _, startsAfter := range []func(prev byte) bool{
+		func(prev byte) bool { return prev == 'v' || prev == 'V' },
+		func(prev byte) bool { return prev == 0 || prev == '-' || prev == '_' },
+		func(prev byte) bool { return prev != '.' && !isDigit(prev) },
+	} {
+		for i := 0; i < len(tag); i++ {
+			var prev byte
+			if i > 0 {
+				prev = tag[i-1]
+			}
+			if !isDigit(tag[i]) || !startsAfter(prev) {
+				continue
+			}
+			if v, ok := Parse(tag[i:]); ok {
+				return v, true
+			}
+		}
+	}
+	return Version{}, false
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// Compare returns -1, 0 or +1 depending on whether v precedes, equals or follows other.
+// A prerelease precedes the release of the same version.
+func (v Version) Compare(other Version) int {
+	if c := compareInt(v.Major, other.Major); c != 0 {
+		return c
+	}
+	if c := compareInt(v.Minor, other.Minor); c != 0 {
+		return c
+	}
+	if c := compareInt(v.Patch, other.Patch); c != 0 {
+		return c
+	}
+	switch {
+	case v.Prerelease == other.Prerelease:
+		return 0
+	case v.Prerelease == "":
+		return 1
+	case other.Prerelease == "":
+		return -1
+	}
+	return comparePrerelease(v.Prerelease, other.Prerelease)
+}
+
+// comparePrerelease compares dot-separated prerelease identifiers: numeric identifiers
+// numerically and before alphanumeric ones, others lexically, and a shorter list first.
+func comparePrerelease(a, b string) int {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		an, aErr := strconv.Atoi(as[i])
+		bn, bErr := strconv.Atoi(bs[i])
+		switch {
+		case aErr == nil && bErr == nil:
+			if c := compareInt(an, bn); c != 0 {
+				return c
+			}
+		case aErr == nil:
+			return -1
+		case bErr == nil:
+			return 1
+		default:
+			if c := strings.Compare(as[i], bs[i]); c != 0 {
+				return c
+			}
+		}
+	}
+	return compareInt(len(as), len(bs))
+}
+
+func compareInt(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
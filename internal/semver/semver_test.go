@@ -0,0 +1,99 @@
+package semver
+
+import "testing"
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		input string
+		want  Version
+		ok    bool
+	}{
+		{"1.2.3", Version{1, 2, 3, ""}, true},
+		{"1.2", Version{1, 2, 0, ""}, true},
+		{"1", Version{1, 0, 0, ""}, true},
+		{"0.0.0", Version{0, 0, 0, ""}, true},
+		{"1.2.3-rc.1", Version{1, 2, 3, "rc.1"}, true},
+		{"1.2.3+build.5", Version{1, 2, 3, ""}, true},
+		{"1.2.3-beta+build", Version{1, 2, 3, "beta"}, true},
+		{"1.2.3.4", Version{}, false},
+		{"01.2.3", Version{}, false},
+		{"1..3", Version{}, false},
+		{"1.x", Version{}, false},
+		{"", Version{}, false},
+		{"v1.2.3", Version{}, false},
+	}
+	for _, tt := range tests {
+		got, ok := Parse(tt.input)
+		if ok != tt.ok || got != tt.want {
+			t.Errorf("Parse(%q) = %+v, %v; want %+v, %v", tt.input, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestParseTag(t *testing.T) {
+	tests := []struct {
+		tag  string
+		want Version
+		ok   bool
+	}{
+		{"v1.2.3", Version{1, 2, 3, ""}, true},
+		{"1.2.3", Version{1, 2, 3, ""}, true},
+		{"release-1.4", Version{1, 4, 0, ""}, true},
+		{"sdk/v2.0.1", Version{2, 0, 1, ""}, true},
+		{"sdk2/v1.2.3", Version{1, 2, 3, ""}, true},
+		{"release-2024/v1.0.0", Version{1, 0, 0, ""}, true},
+		{"v3.0.0-alpha.2", Version{3, 0, 0, "alpha.2"}, true},
+		{"latest", Version{}, false},
+		{"v1.2.3/", Version{}, false},
+		{"sdk2-v1.2.3", Version{1, 2, 3, ""}, true},
+		{"release-2024-v1.0.0", Version{1, 0, 0, ""}, true},
+		{"go1.21.0", Version{1, 21, 0, ""}, true},
+		{"V4.1", Version{4, 1, 0, ""}, true},
+	}
+	for _, tt := range tests {
+		got, ok := ParseTag(tt.tag)
+		if ok != tt.ok || got != tt.want {
+			t.Errorf("ParseTag(%q) = %+v, %v; want %+v, %v", tt.tag, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestCompare(t *testing.T) {
+	// Each version precedes the next one, as in the example of the SemVer specification
+	ordered := []string{
+		"0.9.9",
+		"1.0.0-alpha",
+		"1.0.0-alpha.1",
+		"1.0.0-alpha.beta",
+		"1.0.0-beta",
+		"1.0.0-beta.2",
+		"1.0.0-beta.11",
+		"1.0.0-rc.1",
+		"1.0.0",
+		"1.0.1",
+		"1.1.0",
+		"1.10.0",
+		"2.0.0",
+	}
+	for i := range ordered {
+		for j := range ordered {
+			a, _ := Parse(ordered[i])
+			b, _ := Parse(ordered[j])
+			want := 0
+			if i < j {
+				want = -1
+			} else if i > j {
+				want = 1
+			}
+			if got := a.Compare(b); got != want {
+				t.Errorf("Compare(%s, %s) = %d, want %d", ordered[i], ordered[j], got, want)
+			}
+		}
+	}
+
+	a, _ := Parse("1.2.3+build.1")
+	b, _ := Parse("1.2.3+build.2")
+	if got := a.Compare(b); got != 0 {
+		t.Errorf("Compare ignoring build metadata = %d, want 0", got)
+	}
+}
@@ -0,0 +1,75 @@
+package syncer
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+
+	"syncdocs/internal/database"
+	gh "syncdocs/internal/github"
+	"syncdocs/internal/semver"
+)
+
+// ResolveRef returns the ref to sync for the repository's ref mode: the stored branch, tag
+// or commit SHA, or, for the modes that follow releases, the tag they currently select.
+func ResolveRef(ctx context.Context, client *gh.Client, repo *database.Repository) (string, error) {
+	switch repo.RefMode {
+	case database.RefModeBranch, database.RefModeTag, database.RefModeCommit, "":
+		return repo.Branch, nil
+	case database.RefModeLatestRelease:
+		return client.GetLatestReleaseTag(ctx, repo.Owner, repo.RepoName)
+	case database.RefModeSemver:
+		tags, err := client.ListTagNames(ctx, repo.Owner, repo.RepoName, literalPrefix(repo.RefPattern))
+		if err != nil {
+			return "", err
+		}
+		tag, ok := LatestSemverTag(tags, repo.RefPattern)
+		if !ok {
+			return "", fmt.Errorf("no release tag of %s/%s matches '%s'", repo.Owner, repo.RepoName, repo.RefPattern)
+		}
+		return tag, nil
+	default:
+		return "", fmt.Errorf("unknown ref mode '%s'", repo.RefMode)
+	}
+}
+
+// LatestSemverTag returns the tag with the highest semantic version among the tags matching
+// pattern (a path.Match glob; empty matches every tag). Tags that are not versions and
+// prereleases are skipped.
+func LatestSemverTag(tags []string, pattern string) (string, bool) {
+	var latest string
+	var latestVersion semver.Version
+	for _, tag := range tags {
+		if pattern != "" {
+			if ok, _ := path.Match(pattern, tag); !ok {
+				continue
+			}
+		}
+		version, ok := semver.ParseTag(tag)
+		if !ok || version.Prerelease != "" {
+			continue
+		}
+		if latest == "" || version.Compare(latestVersion) > 0 {
+			latest, latestVersion = tag, version
+		}
+	}
+	return latest, latest != ""
+}
+
+// ValidateRefPattern checks the syntax of a semver ref pattern.
+func ValidateRefPattern(pattern string) error {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return fmt.Errorf("invalid ref pattern '%s': %w", pattern, err)
+	}
+	return nil
+}
+
+// literalPrefix returns the part of a glob before its first wildcard, used to narrow the
+// tags listed from GitHub.
+func literalPrefix(pattern string) string {
+	if i := strings.IndexAny(pattern, `*?[\`); i >= 0 {
+		return pattern[:i]
+	}
+	return pattern
+}
@@ -0,0 +1,66 @@
+package syncer
+
+import "testing"
+
+func TestLatestSemverTag(t *testing.T) {
+	tests := []struct {
+		name    string
+		tags    []string
+		pattern string
+		want    string
+		ok      bool
+	}{
+		{"highest version", []string{"v1.2.0", "v1.10.0", "v1.9.3"}, "", "v1.10.0", true},
+		{"prereleases skipped", []string{"v1.0.0", "v2.0.0-rc.1"}, "", "v1.0.0", true},
+		{"only prereleases", []string{"v2.0.0-rc.1"}, "", "", false},
+		{"non-version tags skipped", []string{"latest", "nightly", "v0.1.0"}, "", "v0.1.0", true},
+		{"pattern restricts the major version", []string{"v1.4.2", "v2.0.0", "v1.10.0"}, "v1.*", "v1.10.0", true},
+		{"empty pattern matches every tag", []string{"sdk/v3.0.0", "v1.0.0"}, "", "sdk/v3.0.0", true},
+		{"pattern with a path", []string{"sdk/v1.2.0", "sdk/v1.3.0", "v9.0.0"}, "sdk/v*", "sdk/v1.3.0", true},
+		{"star does not cross a slash", []string{"sdk/v1.2.0"}, "v*", "", false},
+		{"digits in the prefix", []string{"sdk2/v1.2.3", "sdk2/v1.10.0"}, "sdk2/*", "sdk2/v1.10.0", true},
+		{"no match", []string{"v1.0.0"}, "v2.*", "", false},
+		{"no tags", nil, "", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := LatestSemverTag(tt.tags, tt.pattern)
+			if got != tt.want || ok != tt.ok {
+				t.Errorf("LatestSemverTag(%q, %q) = %q, %v; want %q, %v", tt.tags, tt.pattern, got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestLiteralPrefix(t *testing.T) {
+	tests := []struct {
+		pattern string
+		want    string
+	}{
+		{"", ""},
+		{"v1.*", "v1."},
+		{"sdk/v*", "sdk/v"},
+		{"v?.0.0", "v"},
+		{"[vV]1.*", ""},
+		{`v\*`, "v"},
+		{"v1.2.3", "v1.2.3"},
+	}
+	for _, tt := range tests {
+		if got := literalPrefix(tt.pattern); got != tt.want {
+			t.Errorf("literalPrefix(%q) = %q, want %q", tt.pattern, got, tt.want)
+		}
+	}
+}
+
+func TestValidateRefPattern(t *testing.T) {
+	for _, pattern := range []string{"", "v1.*", "sdk/v[0-9]*"} {
+		if err := ValidateRefPattern(pattern); err != nil {
+			t.Errorf("ValidateRefPattern(%q) returned error: %v", pattern, err)
+		}
+	}
+	for _, pattern := range []string{"v[1.*", `v1\`} {
+		if err := ValidateRefPattern(pattern); err == nil {
+			t.Errorf("ValidateRefPattern(%q) succeeded, want an error", pattern)
+		}
+	}
+}
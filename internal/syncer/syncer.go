@@ -2,45 +2,82 @@ package syncer
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"log"
-	"path/filepath"
+	"path"
 	"sort"
 	"strings"
 	"sync"
 	"time"
 
+	"syncdocs/internal/config"
 	"syncdocs/internal/database"
+	"syncdocs/internal/events"
+	"syncdocs/internal/format"
 	gh "syncdocs/internal/github" // Alias github package
+	"syncdocs/internal/pathfilter"
 )
 
 // Syncer handles the logic for synchronizing repository documents.
 type Syncer struct {
 	Store        *database.RepositoryStore
 	GithubClient *gh.Client
-	syncing      map[int]bool // Tracks repositories currently being synced
-	mu           sync.Mutex   // Protects the syncing map
+	Events       *events.Bus // Receives progress events; may be nil
+	cfg          *config.Config
+	syncing      map[int]context.CancelCauseFunc // Repositories currently being synced by this process
+	mu           sync.Mutex                      // Protects the syncing map
 }
 
+// fileAttemptTimeout limits each GitHub request made to fetch a file.
+const fileAttemptTimeout = 30 * time.Second
+
+var (
+	// ErrSyncInProgress is returned when a repository is already being synced,
+	// by this process or by another instance sharing the database.
+	ErrSyncInProgress = errors.New("sync already in progress")
+	// ErrSyncCancelled is returned when a sync was cancelled on request.
+	ErrSyncCancelled = errors.New("sync cancelled")
+)
+
 // NewSyncer creates a new Syncer instance.
-func NewSyncer(store *database.RepositoryStore, ghClient *gh.Client) *Syncer {
+func NewSyncer(cfg *config.Config, store *database.RepositoryStore, ghClient *gh.Client, bus *events.Bus) *Syncer {
 	return &Syncer{
 		Store:        store,
 		GithubClient: ghClient,
-		syncing:      make(map[int]bool),
+		Events:       bus,
+		cfg:          cfg,
+		syncing:      make(map[int]context.CancelCauseFunc),
 	}
 }
 
+// Triggers recorded with each sync run, describing what started it.
+const (
+	TriggerScheduled = "scheduled"
+	TriggerManual    = "manual"
+	TriggerInitial   = "initial"
+	TriggerWebhook   = "webhook"
+	TriggerRecovery  = "recovery" // Re-run of a sync that died with its process
+	TriggerUpdate    = "update"   // The repository's URL, branch or file selection changed
+)
+
 // SyncRepositoryByID performs the synchronization process for a single repository.
 // It fetches files, aggregates content, and updates the database.
-func (s *Syncer) SyncRepositoryByID(ctx context.Context, id int) error {
+// trigger records what started the sync (see the Trigger* constants) in the run history.
+// The sync can be stopped with CancelSync, in which case ErrSyncCancelled is returned.
+func (s *Syncer) SyncRepositoryByID(ctx context.Context, id int, trigger string) error {
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+
 	s.mu.Lock()
-	if s.syncing[id] {
+	if s.syncing[id] != nil {
 		s.mu.Unlock()
 		log.Printf("Sync already in progress for repository ID: %d. Skipping.", id)
-		return fmt.Errorf("sync already in progress for repository %d", id)
+		return fmt.Errorf("%w for repository %d", ErrSyncInProgress, id)
 	}
-	s.syncing[id] = true
+	s.syncing[id] = cancel
 	s.mu.Unlock()
 
 	// Ensure the syncing status is cleared when the function exits
@@ -51,10 +88,25 @@ func (s *Syncer) SyncRepositoryByID(ctx context.Context, id int) error {
 		log.Printf("Finished sync process for repository ID: %d", id)
 	}()
 
-	log.Printf("Starting sync for repository ID: %d", id)
+	// Outcomes are recorded even if ctx is cancelled, e.g. on cancellation or shutdown
+	recordCtx := context.WithoutCancel(ctx)
+
+	// Take the database-wide lock so that other instances do not sync the same repository
+	lock, err := s.Store.TryLockRepository(ctx, id)
+	if err != nil {
+		return fmt.Errorf("failed to lock repository %d for sync: %w", id, err)
+	}
+	if lock == nil {
+		log.Printf("Sync of repository ID %d is in progress on another instance. Skipping.", id)
+		return fmt.Errorf("%w for repository %d on another instance", ErrSyncInProgress, id)
+	}
+	// Unlock with a fresh context so the lock is released even if ctx was cancelled
+	defer lock.Unlock(context.Background())
+
+	log.Printf("Starting %s sync for repository ID: %d", trigger, id)
 
 	// 1. Mark as syncing in DB
-	err := s.Store.UpdateSyncStatus(ctx, id, "syncing", nil)
+	err = s.Store.UpdateSyncStatus(ctx, id, "syncing", nil)
 	if err != nil {
 		// Log error but proceed if possible, maybe the repo was deleted concurrently?
 		log.Printf("Error marking repo %d as syncing: %v. Proceeding with sync attempt.", id, err)
@@ -64,144 +116,419 @@ func (s *Syncer) SyncRepositoryByID(ctx context.Context, id int) error {
 		}
 	}
 
+	// Keep the sync lease alive so other processes can tell this sync from a dead one;
+	// the heartbeat also picks up cancellation requested through other instances
+	stopHeartbeat := s.startHeartbeat(ctx, id, cancel)
+	defer stopHeartbeat()
+
+	// Record the run in the sync history
+	run, err := s.Store.CreateSyncRun(ctx, id, trigger)
+	if err != nil {
+		// Not fatal: the sync still runs, it just won't appear in the history
+		log.Printf("Error recording sync run for repo %d: %v", id, err)
+		run = &database.SyncRun{RepositoryID: id, Trigger: trigger}
+	}
+	s.Events.Publish(events.Event{Type: events.TypeSyncStarted, RepositoryID: id, RunID: run.ID})
+
+	// Count the GitHub API calls made on behalf of this sync
+	ctx, apiCalls := gh.WithAPICallCounter(ctx)
+
+	err = s.syncRepository(ctx, id, run)
+	run.APICalls = apiCalls.Count()
+	if err != nil && errors.Is(context.Cause(ctx), ErrSyncCancelled) {
+		// Previously stored content is left as it was
+		log.Printf("Sync of repository ID %d cancelled.", id)
+		err = fmt.Errorf("%w for repository %d", ErrSyncCancelled, id)
+		_ = s.Store.UpdateSyncStatus(recordCtx, id, "cancelled", nil)
+		run.Status = "cancelled"
+		s.Events.Publish(events.Event{Type: events.TypeSyncCancel, RepositoryID: id, RunID: run.ID, Status: run.Status})
+	} else if err != nil {
+		_ = s.Store.UpdateSyncStatus(recordCtx, id, "failed", err)
+		run.Status = "failed"
+		run.Error = err.Error()
+		s.Events.Publish(events.Event{Type: events.TypeSyncFailed, RepositoryID: id, RunID: run.ID, Status: run.Status, Error: run.Error})
+	} else {
+		s.Events.Publish(events.Event{Type: events.TypeSyncDone, RepositoryID: id, RunID: run.ID, Status: run.Status})
+	}
+
+	if run.ID != 0 {
+		if finishErr := s.Store.FinishSyncRun(recordCtx, run); finishErr != nil {
+			log.Printf("Error finishing sync run %d for repo %d: %v", run.ID, id, finishErr)
+		}
+	}
+	return err
+}
+
+// CancelSync cancels the sync of a repository running in this process.
+// It reports whether such a sync was found.
+func (s *Syncer) CancelSync(id int) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	cancel := s.syncing[id]
+	if cancel == nil {
+		return false
+	}
+	log.Printf("Cancelling sync of repository ID: %d", id)
+	cancel(ErrSyncCancelled)
+	return true
+}
+
+// startHeartbeat refreshes the sync lease of a repository until the returned function is called.
+// If the repository asks for the sync to stop (see RepositoryStore.RequestSyncCancel), or is
+// no longer marked as syncing, the sync is cancelled through cancelSync.
+func (s *Syncer) startHeartbeat(ctx context.Context, id int, cancelSync context.CancelCauseFunc) (stop func()) {
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		ticker := time.NewTicker(s.cfg.SyncLease / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				// Errors are logged by the store; a missed beat is tolerated by the lease
+				keep, err := s.Store.RefreshSyncHeartbeat(ctx, id)
+				if err == nil && !keep {
+					log.Printf("Sync of repository ID %d was cancelled or taken over elsewhere; stopping.", id)
+					cancelSync(ErrSyncCancelled)
+					return
+				}
+			}
+		}
+	}()
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+// syncRepository lists, fetches and aggregates the files of a repository and stores the result.
+// It fills in the statistics and outcome of run. On error, the caller marks the sync as failed.
+func (s *Syncer) syncRepository(ctx context.Context, id int, run *database.SyncRun) error {
 	// 2. Get repository details from DB
 	repo, err := s.Store.GetRepositoryByID(ctx, id) // Get full details needed for sync
 	if err != nil {
 		log.Printf("Error fetching repository %d details for sync: %v", id, err)
-		_ = s.Store.UpdateSyncStatus(ctx, id, "failed", fmt.Errorf("failed to fetch repository details: %w", err))
-		return err
+		return fmt.Errorf("failed to fetch repository details: %w", err)
 	}
 
-	// 3. Get file list from GitHub
-	log.Printf("Fetching file list for %s/%s (branch: %s) path %s", repo.Owner, repo.RepoName, repo.Branch, repo.DocsPath)
-	filesInfo, err := s.GithubClient.GetRepoContentsRecursive(ctx, repo.Owner, repo.RepoName, repo.DocsPath, repo.Branch)
-	if err != nil {
-		log.Printf("Error getting repo contents for %d (branch: %s): %v", id, repo.Branch, err)
-		_ = s.Store.UpdateSyncStatus(ctx, id, "failed", fmt.Errorf("failed to list GitHub repository contents (branch: %s): %w", repo.Branch, err))
-		return err
+	// 3. Cheap change detection: resolve the ref's head (a free 304 if it has not moved) and,
+	// only if it moved, the tree SHAs of the source paths. If neither changed since the last
+	// successful sync, skip listing and fetching entirely. Manual, initial and update syncs always run.
+	// A pinned tag or commit that was synced once is not even looked up again.
+	canSkip := repo.DocsTreeSHA != "" && run.Trigger != TriggerManual && run.Trigger != TriggerInitial && run.Trigger != TriggerUpdate
+	if canSkip && repo.IsPinned() {
+		run.Ref, run.CommitSHA = repo.Branch, repo.LastCommitSHA
+		return s.markUnchanged(ctx, id, run, repo.LastCommitSHA)
 	}
-	log.Printf("Found %d potential files/dirs for repo %d (branch: %s)", len(filesInfo), id, repo.Branch)
 
+	ref, err := ResolveRef(ctx, s.GithubClient, repo)
+	if err != nil {
+		log.Printf("Error resolving ref for repo %d (mode: %s): %v", id, repo.RefMode, err)
+		return fmt.Errorf("failed to resolve ref (mode: %s): %w", repo.RefMode, err)
+	}
+	run.Ref = ref
 
-	// 4. Filter files by extension
-	allowedExtensions := make(map[string]bool)
-	for _, ext := range strings.Split(repo.Extensions, ",") {
-		trimmedExt := strings.TrimSpace(ext)
-		if trimmedExt != "" {
-			// Store with leading dot for easier matching with filepath.Ext
-			allowedExtensions["."+trimmedExt] = true
-		}
+	commitSHA, changed, err := s.GithubClient.GetCommitSHA(ctx, repo.Owner, repo.RepoName, ref, repo.LastCommitSHA)
+	if err != nil {
+		log.Printf("Error resolving head commit for repo %d (ref: %s): %v", id, ref, err)
+		return fmt.Errorf("failed to resolve head commit (ref: %s): %w", ref, err)
+	}
+	run.CommitSHA = commitSHA
+	if canSkip && !changed {
+		return s.markUnchanged(ctx, id, run, commitSHA)
 	}
 
-	var filesToFetch []gh.FileInfo
-	for _, fileInfo := range filesInfo {
-		ext := strings.ToLower(filepath.Ext(fileInfo.Path))
-		if allowedExtensions[ext] {
-			filesToFetch = append(filesToFetch, fileInfo)
-		}
+	// Trees of the commit are fetched once and shared by the fingerprint, the ignore files and the listing
+	trees := s.GithubClient.NewTrees(repo.Owner, repo.RepoName, commitSHA)
+	treeSHA, err := sourcesTreeSHA(ctx, trees, repo)
+	if err != nil {
+		log.Printf("Error resolving source trees for repo %d (commit: %s): %v", id, commitSHA, err)
+		return fmt.Errorf("failed to resolve source path trees (commit: %s): %w", commitSHA, err)
+	}
+	if canSkip && treeSHA == repo.DocsTreeSHA {
+		return s.markUnchanged(ctx, id, run, commitSHA)
 	}
-	log.Printf("Filtered down to %d files with allowed extensions for repo %d", len(filesToFetch), id)
 
+	// 4. List the files of every source path and filter them with the include/exclude patterns.
+	// Listing and fetching both use the commit resolved above rather than the ref, so a push
+	// during the sync cannot mix files of two versions.
+	s.Events.Publish(events.Event{Type: events.TypeListing, RepositoryID: id, RunID: run.ID})
+	filesToFetch, skippedFiles, err := s.listSourceFiles(ctx, trees, repo, run)
+	if err != nil {
+		return err
+	}
+	log.Printf("Filtered down to %d files matching the patterns for repo %d (%d skipped, %d ignored)", len(filesToFetch), id, len(skippedFiles), run.FilesIgnored)
+	run.FilesSkipped = len(skippedFiles)
+	s.Events.Publish(events.Event{Type: events.TypeFilesFound, RepositoryID: id, RunID: run.ID, Total: len(filesToFetch)})
 
 	if len(filesToFetch) == 0 {
-		log.Printf("No files with allowed extensions found for repo %d. Sync successful (empty).", id)
-		err = s.Store.UpdateSyncSuccess(ctx, id, "") // Store empty content
+		log.Printf("No files matching the patterns found for repo %d. Sync successful (empty).", id)
+		err = s.Store.UpdateSyncSuccess(ctx, id, "", nil, skippedFiles, ref, commitSHA, treeSHA) // Store empty content and clear the file cache
 		if err != nil {
 			log.Printf("Error updating sync success (empty) for repo %d: %v", id, err)
-			// Don't necessarily mark as failed, but log the update error
+			return err
 		}
+		s.recordSnapshot(ctx, id, run, "", nil)
+		run.Status = "success"
 		return nil // Successful sync, just no matching files
 	}
 
-	// 5. Sort files by path
-	sort.Slice(filesToFetch, func(i, j int) bool {
-		return filesToFetch[i].Path < filesToFetch[j].Path
-	})
+	// 5. Load the per-file cache so unchanged files (same blob SHA) can be reused
+	cachedFiles, err := s.Store.GetRepositoryFiles(ctx, id)
+	if err != nil {
+		// Not fatal: fall back to fetching every file
+		log.Printf("Error loading file cache for repo %d, fetching all files: %v", id, err)
+		cachedFiles = nil
+	}
 
-	// 6. Fetch content and aggregate
+	// 6. Fetch changed content and aggregate
 	var aggregatedContent strings.Builder
+	syncedFiles := make([]database.RepositoryFile, 0, len(filesToFetch))
 	totalFilesFetched := 0
-	for _, fileInfo := range filesToFetch {
-		log.Printf("Fetching content for file: %s (Repo ID: %d, Branch: %s)", fileInfo.Path, id, repo.Branch)
-		// Add a timeout to individual file fetches?
-		fileCtx, cancel := context.WithTimeout(ctx, 30*time.Second) // 30-second timeout per file
-		content, err := s.GithubClient.GetFileContent(fileCtx, repo.Owner, repo.RepoName, fileInfo.Path, repo.Branch)
-		cancel() // Release context resources promptly
+	totalFilesReused := 0
+	totalFilesFailed := 0
+	for i, fileInfo := range filesToFetch {
+		progress := events.Event{Type: events.TypeFileFetched, RepositoryID: id, RunID: run.ID, Path: fileInfo.Path, Current: i + 1, Total: len(filesToFetch)}
+		if cached, ok := cachedFiles[fileInfo.Path]; ok && cached.SHA == fileInfo.SHA {
+			aggregatedContent.WriteString(format.MarkdownSection(fileInfo.Path, cached.Content))
+			syncedFiles = append(syncedFiles, cached)
+			totalFilesReused++
+			s.Events.Publish(progress)
+			continue
+		}
+
+		log.Printf("Fetching content for file: %s (Repo ID: %d, Ref: %s, Commit: %s)", fileInfo.Path, id, ref, commitSHA)
+		// Each request of the fetch is limited rather than the whole fetch, which may have to
+		// wait for the rate limit to reset
+		fileCtx := gh.WithAttemptTimeout(ctx, fileAttemptTimeout)
+		content, err := s.GithubClient.GetFileContent(fileCtx, repo.Owner, repo.RepoName, fileInfo.Path, commitSHA)
 
 		if err != nil {
-			log.Printf("Error getting file content for %s (Repo ID: %d, Branch: %s): %v", fileInfo.Path, id, repo.Branch, err)
-			// Decide whether to fail the whole sync or just skip this file
-			// For now, fail the whole sync on any file error
-			_ = s.Store.UpdateSyncStatus(ctx, id, "failed", fmt.Errorf("failed to get content for file '%s' (branch: %s): %w", fileInfo.Path, repo.Branch, err))
-			return err
+			log.Printf("Error getting file content for %s (Repo ID: %d, Ref: %s): %v", fileInfo.Path, id, ref, err)
+			if repo.AllowPartial {
+				// Skip the failing file and keep going; the sync will be marked partial
+				skippedFiles = append(skippedFiles, database.SkippedFile{Path: fileInfo.Path, Reason: err.Error()})
+				totalFilesFailed++
+				progress.Error = err.Error()
+				s.Events.Publish(progress)
+				continue
+			}
+			return fmt.Errorf("failed to get content for file '%s' (ref: %s): %w", fileInfo.Path, ref, err)
 		}
 
-		// Add separator and content
-		aggregatedContent.WriteString("---\n")
-		aggregatedContent.WriteString(fmt.Sprintf("File: %s\n", fileInfo.Path))
-		aggregatedContent.WriteString("---\n\n")
-		aggregatedContent.WriteString(content)
-		aggregatedContent.WriteString("\n\n\n") // Add extra newlines between files
+		aggregatedContent.WriteString(format.MarkdownSection(fileInfo.Path, content))
+		syncedFiles = append(syncedFiles, database.RepositoryFile{RepositoryID: id, Path: fileInfo.Path, SHA: fileInfo.SHA, Content: content})
 		totalFilesFetched++
+		run.BytesFetched += int64(len(content))
+		s.Events.Publish(progress)
 	}
+	run.FilesFetched = totalFilesFetched
+	run.FilesReused = totalFilesReused
+	run.FilesSkipped = len(skippedFiles)
 
-	// 7. Update database with aggregated content
-	log.Printf("Successfully fetched content for %d files for repo %d. Updating database.", totalFilesFetched, id)
+	// 7. Update database with aggregated content and the per-file cache; deleted files are dropped from it
+	log.Printf("Fetched content for %d files and reused %d cached files for repo %d. Updating database.", totalFilesFetched, totalFilesReused, id)
 	finalContent := aggregatedContent.String()
-	err = s.Store.UpdateSyncSuccess(ctx, id, finalContent)
+	if totalFilesFailed > 0 {
+		log.Printf("%d of %d files failed to fetch for repo %d. Storing partial content.", totalFilesFailed, len(filesToFetch), id)
+		partialErr := fmt.Errorf("%d of %d files failed to sync", totalFilesFailed, len(filesToFetch))
+		err = s.Store.UpdateSyncPartial(ctx, id, finalContent, syncedFiles, skippedFiles, ref, commitSHA, partialErr)
+		run.Status = "partial"
+		run.Error = partialErr.Error()
+	} else {
+		err = s.Store.UpdateSyncSuccess(ctx, id, finalContent, syncedFiles, skippedFiles, ref, commitSHA, treeSHA)
+		run.Status = "success"
+	}
 	if err != nil {
 		log.Printf("Error updating sync success data for repo %d: %v", id, err)
-		// Don't mark as failed if content was fetched but DB update failed, but log it.
-		// The status might remain 'syncing' or revert based on previous state.
-		// Consider how to handle DB update failures more robustly.
-		return err // Return the DB error
+		return err // Return the DB error; the caller marks the sync as failed
 	}
 
-	log.Printf("Sync successful for repository ID: %d", id)
+	// 8. Keep an immutable snapshot if the content changed
+	s.recordSnapshot(ctx, id, run, finalContent, syncedFiles)
+
+	log.Printf("Sync completed for repository ID: %d (%d failed files)", id, totalFilesFailed)
 	return nil
 }
 
-// SyncAllRepositories iterates through all configured repositories and triggers their sync.
-// This is intended to be called by a scheduler.
-// Consider adding concurrency limits if syncing many repos.
-func (s *Syncer) SyncAllRepositories(ctx context.Context) {
-	log.Println("Starting scheduled sync for all repositories...")
-	repos, err := s.Store.GetAllRepositoriesForSync(ctx)
-	if err != nil {
-		log.Printf("Error retrieving repositories for scheduled sync: %v", err)
-		return
+// sourcesTreeSHA returns a fingerprint of the source paths at a commit, used to detect
+// whether any of them changed. For a single source path this is its tree (or blob) SHA;
+// for several, or when a root .syncdocsignore lies outside them, it is a SHA-256 over
+// each path and its SHA, in order.
+func sourcesTreeSHA(ctx context.Context, trees *gh.Trees, repo *database.Repository) (string, error) {
+	sources := repo.EffectiveSourcePaths()
+	rootIgnoreSHA := ""
+	if !hasRootSource(sources) {
+		var err error
+		rootIgnoreSHA, err = trees.SHA(ctx, pathfilter.IgnoreFileName)
+		if err != nil {
+			return "", err
+		}
+	}
+	if len(sources) == 1 && rootIgnoreSHA == "" {
+		return trees.SHA(ctx, sources[0].Path)
+	}
+
+	hash := sha256.New()
+	if rootIgnoreSHA != "" {
+		fmt.Fprintf(hash, "%s\x00%s\n", pathfilter.IgnoreFileName, rootIgnoreSHA)
+	}
+	for _, source := range sources {
+		sha, err := trees.SHA(ctx, source.Path)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(hash, "%s\x00%s\n", source.Path, sha)
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// hasRootSource reports whether one of the source paths is the repository root.
+func hasRootSource(sources []database.SourcePath) bool {
+	for _, source := range sources {
+		if strings.Trim(source.Path, "/") == "" {
+			return true
+		}
 	}
+	return false
+}
 
-	log.Printf("Found %d repositories to potentially sync.", len(repos))
+// loadIgnore fetches the .syncdocsignore files at the repository root and at the top of each
+// source path, root first so that deeper files take precedence, and records the effective
+// rules on run. Missing files are skipped.
+func (s *Syncer) loadIgnore(ctx context.Context, trees *gh.Trees, repo *database.Repository, run *database.SyncRun) (*pathfilter.Ignore, error) {
+	candidates := []string{pathfilter.IgnoreFileName}
+	seen := map[string]bool{pathfilter.IgnoreFileName: true}
+	for _, source := range repo.EffectiveSourcePaths() {
+		candidate := path.Join(source.Path, pathfilter.IgnoreFileName)
+		if !seen[candidate] {
+			seen[candidate] = true
+			candidates = append(candidates, candidate)
+		}
+	}
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return strings.Count(candidates[i], "/") < strings.Count(candidates[j], "/")
+	})
 
-	var wg sync.WaitGroup
-	// Simple concurrency limit: process N at a time
-	concurrencyLimit := 5 // Adjust as needed
-	semaphore := make(chan struct{}, concurrencyLimit)
+	ignore := &pathfilter.Ignore{}
+	for _, candidate := range candidates {
+		sha, err := trees.SHA(ctx, candidate)
+		if err != nil {
+			return nil, fmt.Errorf("failed to look up %s: %w", candidate, err)
+		}
+		if sha == "" {
+			continue // No ignore file here (or the source path is a file)
+		}
+		content, err := s.GithubClient.GetBlobContent(ctx, repo.Owner, repo.RepoName, sha)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch %s: %w", candidate, err)
+		}
+		for _, rule := range ignore.Add(candidate, content) {
+			run.IgnoreRules = append(run.IgnoreRules, candidate+": "+rule)
+		}
+	}
+	if ignore.Len() > 0 {
+		log.Printf("Loaded %d ignore rules for repo %d", ignore.Len(), repo.ID)
+	}
+	return ignore, nil
+}
 
+// listSourceFiles lists the files of each source path of a repository, in order, and keeps
+// those selected by the source's patterns (or the repository's, if it has none) and not
+// excluded by a .syncdocsignore file. Files of a source are sorted by path; a file reached
+// through several sources is only kept the first time. Files over the size cap are returned
+// as skipped.
+func (s *Syncer) listSourceFiles(ctx context.Context, trees *gh.Trees, repo *database.Repository, run *database.SyncRun) ([]gh.FileInfo, []database.SkippedFile, error) {
+	var filesToFetch []gh.FileInfo
+	var skippedFiles []database.SkippedFile
+	seen := make(map[string]bool)
 
-	for _, repo := range repos {
-		wg.Add(1)
-		semaphore <- struct{}{} // Acquire semaphore slot
+	ignore, err := s.loadIgnore(ctx, trees, repo, run)
+	if err != nil {
+		log.Printf("Error loading ignore files for repo %d: %v", repo.ID, err)
+		return nil, nil, fmt.Errorf("failed to load %s: %w", pathfilter.IgnoreFileName, err)
+	}
 
-		go func(r database.Repository) {
-			defer wg.Done()
-			defer func() { <-semaphore }() // Release semaphore slot
+	for _, source := range repo.EffectiveSourcePaths() {
+		log.Printf("Fetching file list for %s/%s (ref: %s, commit: %s) path %s", repo.Owner, repo.RepoName, run.Ref, run.CommitSHA, source.Path)
+		filesInfo, err := trees.ListFiles(ctx, source.Path)
+		if err != nil {
+			log.Printf("Error getting repo contents for %d (ref: %s, path: %s): %v", repo.ID, run.Ref, source.Path, err)
+			return nil, nil, fmt.Errorf("failed to list GitHub repository contents (ref: %s, path: %s): %w", run.Ref, source.Path, err)
+		}
+		log.Printf("Found %d files under %s for repo %d (ref: %s)", len(filesInfo), source.Path, repo.ID, run.Ref)
+		run.FilesListed += len(filesInfo)
 
-			// Create a new context for each sync operation? Or use the main one?
-			// Using the main context means if it's cancelled, all ongoing syncs might stop.
-			syncCtx := ctx // Use the passed context for now
+		patterns := source.Patterns
+		if len(patterns) == 0 {
+			patterns = repo.Patterns
+		}
+		filter, err := pathfilter.New(patterns)
+		if err != nil {
+			log.Printf("Invalid patterns for repo %d (path: %s): %v", repo.ID, source.Path, err)
+			return nil, nil, fmt.Errorf("invalid file patterns for source path '%s': %w", source.Path, err)
+		}
 
-			err := s.SyncRepositoryByID(syncCtx, r.ID)
-			if err != nil {
-				// Error is already logged within SyncRepositoryByID
-				log.Printf("Scheduled sync for repo ID %d completed with error: %v", r.ID, err)
-			} else {
-				log.Printf("Scheduled sync for repo ID %d completed successfully.", r.ID)
+		sort.Slice(filesInfo, func(i, j int) bool {
+			return filesInfo[i].Path < filesInfo[j].Path
+		})
+		for _, fileInfo := range filesInfo {
+			if seen[fileInfo.Path] || !filter.Match(fileInfo.Path) || path.Base(fileInfo.Path) == pathfilter.IgnoreFileName {
+				continue
+			}
+			seen[fileInfo.Path] = true
+			if ignore.Match(fileInfo.Path) {
+				run.FilesIgnored++
+				continue
 			}
-		}(repo)
+			// Skip files over the size cap rather than failing the whole sync
+			if s.cfg.MaxFileSize > 0 && fileInfo.Size > s.cfg.MaxFileSize {
+				log.Printf("Skipping file %s for repo %d: size %d bytes exceeds limit of %d bytes", fileInfo.Path, repo.ID, fileInfo.Size, s.cfg.MaxFileSize)
+				skippedFiles = append(skippedFiles, database.SkippedFile{
+					Path:   fileInfo.Path,
+					Reason: fmt.Sprintf("file size %d bytes exceeds limit of %d bytes", fileInfo.Size, s.cfg.MaxFileSize),
+				})
+				continue
+			}
+			filesToFetch = append(filesToFetch, fileInfo)
+		}
 	}
+	return filesToFetch, skippedFiles, nil
+}
+
+// markUnchanged records a sync that found no upstream changes since the last successful sync.
+func (s *Syncer) markUnchanged(ctx context.Context, id int, run *database.SyncRun, commitSHA string) error {
+	log.Printf("No upstream changes for repo %d (commit: %s). Skipping sync.", id, commitSHA)
+	if err := s.Store.UpdateSyncUnchanged(ctx, id, run.Ref, commitSHA); err != nil {
+		return err
+	}
+	run.Status = "unchanged"
+	return nil
+}
+
+// recordSnapshot stores the synced content as a snapshot (skipped if unchanged since the
+// latest one) and prunes snapshots that fall outside the retention policy.
+// Errors are logged only: the content itself is already stored at this point.
+func (s *Syncer) recordSnapshot(ctx context.Context, id int, run *database.SyncRun, content string, files []database.RepositoryFile) {
+	snapshot, created, err := s.Store.CreateSnapshot(ctx, id, run.CommitSHA, content, files)
+	if err != nil {
+		log.Printf("Error creating snapshot for repo %d: %v", id, err)
+		return
+	}
+	if !created {
+		log.Printf("Content unchanged for repo %d since snapshot %d; no new snapshot created.", id, snapshot.ID)
+		return
+	}
+	log.Printf("Created snapshot %d for repo %d", snapshot.ID, id)
 
-	wg.Wait() // Wait for all goroutines to finish
-	log.Println("Finished scheduled sync run for all repositories.")
+	pruned, err := s.Store.PruneSnapshots(ctx, id, s.cfg.SnapshotKeepLast, s.cfg.SnapshotMaxAge)
+	if err != nil {
+		log.Printf("Error pruning snapshots for repo %d: %v", id, err)
+	} else if pruned > 0 {
+		log.Printf("Pruned %d old snapshots for repo %d", pruned, id)
+	}
 }
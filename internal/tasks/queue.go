@@ -0,0 +1,198 @@
+package tasks
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"syncdocs/internal/config"
+	"syncdocs/internal/database"
+	"syncdocs/internal/syncer"
+)
+
+const (
+	// pollInterval is how often idle workers check the database for due jobs.
+	// Jobs enqueued by this process wake a worker immediately.
+	pollInterval = 5 * time.Second
+	// retryBaseDelay is the delay before the first retry of a failed job; it doubles per attempt.
+	retryBaseDelay = 30 * time.Second
+	// busyRetryDelay is how long a job waits when its repository is already being synced.
+	busyRetryDelay = 30 * time.Second
+)
+
+// Queue processes sync jobs stored in the database with a pool of workers.
+// Because jobs are claimed with FOR UPDATE SKIP LOCKED, several processes can share one queue.
+type Queue struct {
+	store  *database.RepositoryStore
+	syncer *syncer.Syncer
+	cfg    *config.Config
+	wake   chan struct{} // Signals idle workers that a job was enqueued
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewQueue creates a new Queue.
+func NewQueue(cfg *config.Config, store *database.RepositoryStore, syncer *syncer.Syncer) *Queue {
+	return &Queue{
+		store:  store,
+		syncer: syncer,
+		cfg:    cfg,
+		wake:   make(chan struct{}, 1),
+	}
+}
+
+// Enqueue queues a sync of a repository. If a job for it is already queued, that job is returned.
+func (q *Queue) Enqueue(ctx context.Context, repoID int, trigger string) (*database.SyncJob, error) {
+	job, created, err := q.store.EnqueueSyncJob(ctx, repoID, trigger, q.cfg.SyncJobMaxAttempts)
+	if err != nil {
+		return nil, err
+	}
+	if created {
+		log.Printf("Enqueued %s sync job %d for repository ID: %d", trigger, job.ID, repoID)
+		q.notify()
+	} else {
+		log.Printf("Sync job %d already queued for repository ID: %d", job.ID, repoID)
+	}
+	return job, nil
+}
+
+// Start recovers syncs left behind by dead processes and launches the worker pool.
+func (q *Queue) Start() {
+	ctx, cancel := context.WithCancel(context.Background())
+	q.cancel = cancel
+
+	q.wg.Add(1)
+	go q.recoverer(ctx)
+
+	log.Printf("Starting sync job queue with %d workers...", q.cfg.SyncWorkers)
+	for i := 0; i < q.cfg.SyncWorkers; i++ {
+		q.wg.Add(1)
+		go q.worker(ctx)
+	}
+}
+
+// Stop signals the workers to stop and waits for them. Jobs interrupted mid-sync
+// are returned to the queue.
+func (q *Queue) Stop() {
+	if q.cancel == nil {
+		return
+	}
+	log.Println("Stopping sync job queue...")
+	q.cancel()
+	q.wg.Wait()
+	log.Println("Sync job queue stopped.")
+}
+
+// notify wakes one idle worker, if any.
+func (q *Queue) notify() {
+	select {
+	case q.wake <- struct{}{}:
+	default: // A wake-up is already pending
+	}
+}
+
+// recoverer periodically looks for syncs whose lease expired, either at startup after a
+// crash or because another process died, marks them failed and queues them again.
+func (q *Queue) recoverer(ctx context.Context) {
+	defer q.wg.Done()
+
+	ticker := time.NewTicker(q.cfg.SyncLease)
+	defer ticker.Stop()
+	for {
+		q.recoverStaleSyncs(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// recoverStaleSyncs resets stale "syncing" repositories and re-enqueues them.
+func (q *Queue) recoverStaleSyncs(ctx context.Context) {
+	ids, err := q.store.RecoverStaleSyncs(ctx, q.cfg.SyncLease)
+	if err != nil {
+		return // Logged by the store; retried on the next tick
+	}
+	for _, id := range ids {
+		log.Printf("Recovered stale sync of repository ID: %d", id)
+		if _, err := q.Enqueue(ctx, id, syncer.TriggerRecovery); err != nil {
+			log.Printf("Error re-enqueuing recovered sync for repo ID %d: %v", id, err)
+		}
+	}
+}
+
+// worker claims and processes jobs until ctx is cancelled.
+func (q *Queue) worker(ctx context.Context) {
+	defer q.wg.Done()
+
+	timer := time.NewTimer(0) // Check for due jobs right away
+	defer timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-q.wake:
+		case <-timer.C:
+		}
+
+		// Drain the queue before going idle again
+		for ctx.Err() == nil {
+			job, err := q.store.ClaimSyncJob(ctx)
+			if err != nil || job == nil {
+				break
+			}
+			q.process(ctx, job)
+		}
+
+		timer.Reset(pollInterval)
+	}
+}
+
+// process runs a claimed job and records its outcome, scheduling a retry with
+// exponential backoff while attempts remain.
+func (q *Queue) process(ctx context.Context, job *database.SyncJob) {
+	log.Printf("Processing sync job %d (repository ID: %d, attempt %d/%d)", job.ID, job.RepositoryID, job.Attempts, job.MaxAttempts)
+	err := q.syncer.SyncRepositoryByID(ctx, job.RepositoryID, job.Trigger)
+
+	// Record the outcome even if the queue is shutting down
+	recordCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	switch {
+	case err == nil:
+		if err := q.store.CompleteSyncJob(recordCtx, job.ID); err != nil {
+			log.Printf("Error completing sync job %d: %v", job.ID, err)
+		}
+		log.Printf("Sync job %d succeeded.", job.ID)
+	case ctx.Err() != nil:
+		if err := q.store.ReleaseSyncJob(recordCtx, job.ID, time.Now()); err != nil {
+			log.Printf("Error releasing interrupted sync job %d: %v", job.ID, err)
+		}
+		log.Printf("Sync job %d interrupted by shutdown; returned to the queue.", job.ID)
+	case errors.Is(err, syncer.ErrSyncInProgress):
+		// Not a failure: wait for the running sync (here or on another instance) to finish
+		if err := q.store.ReleaseSyncJob(recordCtx, job.ID, time.Now().Add(busyRetryDelay)); err != nil {
+			log.Printf("Error postponing sync job %d: %v", job.ID, err)
+		}
+		log.Printf("Sync job %d postponed; repository ID %d is already being synced.", job.ID, job.RepositoryID)
+	case errors.Is(err, syncer.ErrSyncCancelled):
+		if err := q.store.MarkSyncJobCancelled(recordCtx, job.ID); err != nil {
+			log.Printf("Error marking sync job %d as cancelled: %v", job.ID, err)
+		}
+		log.Printf("Sync job %d cancelled.", job.ID)
+	case job.Attempts < job.MaxAttempts:
+		retryAt := time.Now().Add(retryBaseDelay << (job.Attempts - 1))
+		if err := q.store.FailSyncJob(recordCtx, job.ID, err, &retryAt); err != nil {
+			log.Printf("Error scheduling retry of sync job %d: %v", job.ID, err)
+		}
+		log.Printf("Sync job %d failed (%v); retrying at %s.", job.ID, err, retryAt.Format(time.RFC3339))
+	default:
+		if err := q.store.FailSyncJob(recordCtx, job.ID, err, nil); err != nil {
+			log.Printf("Error marking sync job %d as failed: %v", job.ID, err)
+		}
+		log.Printf("Sync job %d failed after %d attempts: %v", job.ID, job.Attempts, err)
+	}
+}
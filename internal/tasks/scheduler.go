@@ -4,32 +4,89 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/robfig/cron/v3"
 
 	"syncdocs/internal/config"
+	"syncdocs/internal/database"
 	"syncdocs/internal/syncer"
 )
 
+// leaderCheckInterval is how often a scheduler campaigns for leadership, or verifies that
+// it still holds it. Repository schedules are reloaded at the same interval.
+const leaderCheckInterval = 15 * time.Second
+
+// ScheduleManual is the repository schedule that disables scheduled syncs.
+const ScheduleManual = "manual"
+
+// minScheduleInterval is the shortest "@every" interval accepted for a repository, so that
+// one repository cannot use up the GitHub rate limit. Cron expressions cannot be more
+// frequent than once a minute anyway.
+const minScheduleInterval = time.Minute
+
+// NormalizeSchedule validates a repository schedule and returns it in canonical form.
+// A schedule is empty (use SYNC_INTERVAL), "manual" (never sync on a schedule), a standard
+// five-field cron expression, or a descriptor such as "@daily" or "@every 10m" (at least
+// one minute).
+func NormalizeSchedule(schedule string) (string, error) {
+	schedule = strings.TrimSpace(schedule)
+	if schedule == "" {
+		return "", nil
+	}
+	if strings.EqualFold(schedule, ScheduleManual) {
+		return ScheduleManual, nil
+	}
+	parsed, err := cron.ParseStandard(schedule)
+	if err != nil {
+		return "", fmt.Errorf("invalid schedule '%s': %w", schedule, err)
+	}
+	if every, ok := parsed.(cron.ConstantDelaySchedule); ok && every.Delay < minScheduleInterval {
+		return "", fmt.Errorf("invalid schedule '%s': interval must be at least %s", schedule, minScheduleInterval)
+	}
+	return schedule, nil
+}
+
+// scheduleEntry is the cron entry that syncs one repository. Manually synced repositories
+// have an entry with the "manual" spec and no cron entry, so that reloads skip them.
+type scheduleEntry struct {
+	spec string
+	id   cron.EntryID
+}
+
 // Scheduler manages scheduled tasks.
+// When several instances share the database, only the one holding the scheduler
+// leadership lock runs them; the others take over if the leader goes away.
 type Scheduler struct {
-	cron   *cron.Cron
-	syncer *syncer.Syncer
-	cfg    *config.Config
+	cron  *cron.Cron
+	store *database.RepositoryStore
+	queue *Queue
+	cfg   *config.Config
+
+	mu         sync.Mutex
+	leaderLock *database.AdvisoryLock // Non-nil while this instance is the leader
+	stopElect  context.CancelFunc
+	electDone  chan struct{}
+
+	entriesMu sync.Mutex
+	entries   map[int]scheduleEntry // Keyed by repository ID
 }
 
 // NewScheduler creates a new Scheduler.
-func NewScheduler(cfg *config.Config, syncer *syncer.Syncer) *Scheduler {
+func NewScheduler(cfg *config.Config, store *database.RepositoryStore, queue *Queue) *Scheduler {
 	// Create a new cron scheduler with seconds field support (optional)
 	// c := cron.New(cron.WithSeconds())
 	// Or standard cron without seconds:
 	c := cron.New()
 
 	return &Scheduler{
-		cron:   c,
-		syncer: syncer,
-		cfg:    cfg,
+		cron:    c,
+		store:   store,
+		queue:   queue,
+		cfg:     cfg,
+		entries: make(map[int]scheduleEntry),
 	}
 }
 
@@ -37,30 +94,17 @@ func NewScheduler(cfg *config.Config, syncer *syncer.Syncer) *Scheduler {
 func (s *Scheduler) Start() {
 	log.Println("Starting task scheduler...")
 
-	// Schedule the SyncAllRepositories task based on the configured interval.
-	// cron format: "Minute Hour DayOfMonth Month DayOfWeek"
-	// We need to convert the duration interval into a cron spec.
-	// This is a bit tricky for arbitrary durations. A common approach is
-	// to run frequently (e.g., every minute) and check internally if the
-	// interval has passed, OR use a simpler cron spec like "@hourly", "@daily".
-	// For simplicity, let's use the interval directly if it's a standard one,
-	// otherwise default to hourly.
-
-	// Convert duration to a cron-compatible spec string (simplistic approach)
-	intervalSpec := fmt.Sprintf("@every %s", s.cfg.SyncInterval.String())
-	log.Printf("Scheduling 'SyncAllRepositories' with spec: %s", intervalSpec)
-
-	// Add the job to the scheduler
-	_, err := s.cron.AddFunc(intervalSpec, func() {
-		log.Println("Running scheduled task: SyncAllRepositories")
-		// Use context.Background() for scheduled tasks as they are not tied to requests
-		s.syncer.SyncAllRepositories(context.Background())
-	})
-
-	if err != nil {
-		log.Fatalf("Error scheduling sync task: %v", err)
+	// Add one entry per repository; entries are kept up to date as repositories change
+	if err := s.Refresh(context.Background()); err != nil {
+		log.Printf("Error loading repository schedules: %v", err)
 	}
 
+	// Campaign for leadership in the background
+	ctx, cancel := context.WithCancel(context.Background())
+	s.stopElect = cancel
+	s.electDone = make(chan struct{})
+	go s.elect(ctx)
+
 	// Start the cron scheduler in a new goroutine
 	go s.cron.Start()
 
@@ -78,4 +122,162 @@ func (s *Scheduler) Stop() {
 	case <-time.After(10 * time.Second): // Add a timeout for shutdown
 		log.Println("Task scheduler shutdown timed out.")
 	}
+
+	// Step down so another instance can take over right away
+	if s.stopElect != nil {
+		s.stopElect()
+		<-s.electDone
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.leaderLock != nil {
+		s.leaderLock.Unlock(context.Background())
+		s.leaderLock = nil
+		log.Println("Released scheduler leadership.")
+	}
+}
+
+// IsLeader reports whether this instance currently runs the scheduled tasks.
+func (s *Scheduler) IsLeader() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.leaderLock != nil
+}
+
+// elect keeps trying to become the scheduler leader and, once leader, checks that the
+// connection holding the leadership lock is still alive, until ctx is cancelled.
+func (s *Scheduler) elect(ctx context.Context) {
+	defer close(s.electDone)
+
+	ticker := time.NewTicker(leaderCheckInterval)
+	defer ticker.Stop()
+	for {
+		s.campaign(ctx)
+		if err := s.Refresh(ctx); err != nil && ctx.Err() == nil {
+			log.Printf("Error refreshing repository schedules: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// campaign takes the leadership lock if it is free, or drops leadership if the lock was lost.
+func (s *Scheduler) campaign(ctx context.Context) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.leaderLock != nil {
+		if s.leaderLock.Alive(ctx) || ctx.Err() != nil {
+			return
+		}
+		// The server released the lock along with the connection
+		log.Println("Lost scheduler leadership: the database connection holding it is gone.")
+		s.leaderLock.Unlock(ctx)
+		s.leaderLock = nil
+	}
+
+	lock, err := s.store.TryLockSchedulerLeader(ctx)
+	if err != nil || lock == nil {
+		return // Errors are logged by the store; another instance may be the leader
+	}
+	s.leaderLock = lock
+	log.Println("Became scheduler leader; this instance runs the scheduled tasks.")
+}
+
+// Refresh reloads the schedules of all repositories, adding, updating and removing entries
+// so that they match the database. This also picks up changes made through other instances.
+func (s *Scheduler) Refresh(ctx context.Context) error {
+	repos, err := s.store.GetAllRepositoriesForSync(ctx)
+	if err != nil {
+		return err
+	}
+
+	present := make(map[int]bool, len(repos))
+	for i := range repos {
+		present[repos[i].ID] = true
+		s.ScheduleRepository(&repos[i])
+	}
+
+	s.entriesMu.Lock()
+	var removed []int
+	for id := range s.entries {
+		if !present[id] {
+			removed = append(removed, id)
+		}
+	}
+	s.entriesMu.Unlock()
+	for _, id := range removed {
+		s.UnscheduleRepository(id)
+	}
+	return nil
+}
+
+// ScheduleRepository creates or updates the entry syncing a repository on its schedule.
+// An empty schedule falls back to SYNC_INTERVAL; "manual" removes the entry, as does
+// pinning the repository to a tag or commit, whose content never changes.
+func (s *Scheduler) ScheduleRepository(repo *database.Repository) {
+	repoID := repo.ID
+	spec := repo.Schedule
+	if spec == "" {
+		spec = fmt.Sprintf("@every %s", s.cfg.SyncInterval.String())
+	}
+	if repo.IsPinned() {
+		spec = ScheduleManual
+	}
+
+	s.entriesMu.Lock()
+	defer s.entriesMu.Unlock()
+
+	existing, ok := s.entries[repoID]
+	if ok && existing.spec == spec {
+		return
+	}
+	if ok {
+		if existing.spec != ScheduleManual {
+			s.cron.Remove(existing.id)
+		}
+		delete(s.entries, repoID)
+	}
+	if spec == ScheduleManual {
+		s.entries[repoID] = scheduleEntry{spec: ScheduleManual}
+		log.Printf("Repository ID %d is synced manually only; no schedule entry.", repoID)
+		return
+	}
+
+	entryID, err := s.cron.AddFunc(spec, func() { s.enqueueScheduled(repoID) })
+	if err != nil {
+		// Schedules are validated on input, so this only happens for rows edited by hand
+		log.Printf("Error scheduling sync of repository ID %d with spec '%s': %v", repoID, spec, err)
+		return
+	}
+	s.entries[repoID] = scheduleEntry{spec: spec, id: entryID}
+	log.Printf("Scheduled sync of repository ID %d with spec: %s", repoID, spec)
+}
+
+// UnscheduleRepository removes the entry of a repository, e.g. after it was deleted.
+func (s *Scheduler) UnscheduleRepository(repoID int) {
+	s.entriesMu.Lock()
+	defer s.entriesMu.Unlock()
+
+	if existing, ok := s.entries[repoID]; ok {
+		delete(s.entries, repoID)
+		if existing.spec != ScheduleManual {
+			s.cron.Remove(existing.id)
+			log.Printf("Removed sync schedule of repository ID %d.", repoID)
+		}
+	}
+}
+
+// enqueueScheduled queues a scheduled sync of a repository if this instance is the leader.
+func (s *Scheduler) enqueueScheduled(repoID int) {
+	if !s.IsLeader() {
+		return // The leader enqueues scheduled syncs for all instances
+	}
+	// Use context.Background() for scheduled tasks as they are not tied to requests
+	if _, err := s.queue.Enqueue(context.Background(), repoID, syncer.TriggerScheduled); err != nil {
+		log.Printf("Error enqueuing scheduled sync for repo ID %d: %v", repoID, err)
+	}
 }
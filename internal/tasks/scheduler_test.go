@@ -0,0 +1,33 @@
+package tasks
+
+import "testing"
+
+func TestNormalizeSchedule(t *testing.T) {
+	tests := []struct {
+		schedule string
+		want     string
+		ok       bool
+	}{
+		{"", "", true},
+		{"   ", "", true},
+		{"manual", ScheduleManual, true},
+		{"MANUAL", ScheduleManual, true},
+		{" Manual ", ScheduleManual, true},
+		{"*/15 * * * *", "*/15 * * * *", true},
+		{" 0 3 * * 1-5 ", "0 3 * * 1-5", true},
+		{"@daily", "@daily", true},
+		{"@every 10m", "@every 10m", true},
+		{"@every 1m", "@every 1m", true},
+		{"@every 30s", "", false},
+		{"@every 59s", "", false},
+		{"not a schedule", "", false},
+		{"* * * *", "", false},
+		{"61 * * * *", "", false},
+	}
+	for _, tt := range tests {
+		got, err := NormalizeSchedule(tt.schedule)
+		if (err == nil) != tt.ok || got != tt.want {
+			t.Errorf("NormalizeSchedule(%q) = %q, %v; want %q, ok %v", tt.schedule, got, err, tt.want, tt.ok)
+		}
+	}
+}
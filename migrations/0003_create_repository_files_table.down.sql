@@ -0,0 +1 @@
+DROP TABLE IF EXISTS repository_files;
\ No newline at end of file
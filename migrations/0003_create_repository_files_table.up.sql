@@ -0,0 +1,12 @@
+-- Per-file cache used for incremental syncs
+CREATE TABLE IF NOT EXISTS repository_files (
+    repository_id INTEGER NOT NULL REFERENCES repositories(id) ON DELETE CASCADE,
+    path TEXT NOT NULL,
+    sha VARCHAR(64) NOT NULL,
+    content TEXT NOT NULL,
+    updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (repository_id, path)
+);
+
+COMMENT ON TABLE repository_files IS 'Cached content of each synced file, keyed by repository and path';
+COMMENT ON COLUMN repository_files.sha IS 'Git blob SHA of the cached content';
@@ -0,0 +1,2 @@
+ALTER TABLE repositories
+DROP COLUMN skipped_files;
\ No newline at end of file
@@ -0,0 +1,4 @@
+ALTER TABLE repositories
+ADD COLUMN skipped_files JSONB;
+
+COMMENT ON COLUMN repositories.skipped_files IS 'Files left out of the last sync and why, as a JSON array of {path, reason}';
@@ -0,0 +1,2 @@
+ALTER TABLE repositories
+DROP COLUMN allow_partial;
\ No newline at end of file
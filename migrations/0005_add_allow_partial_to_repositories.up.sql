@@ -0,0 +1,4 @@
+ALTER TABLE repositories
+ADD COLUMN allow_partial BOOLEAN NOT NULL DEFAULT FALSE;
+
+COMMENT ON COLUMN repositories.allow_partial IS 'Skip files that fail to fetch and mark the sync as partial instead of failed';
@@ -0,0 +1,23 @@
+-- History of sync runs, one row per sync attempt
+CREATE TABLE IF NOT EXISTS sync_runs (
+    id SERIAL PRIMARY KEY,
+    repository_id INTEGER NOT NULL REFERENCES repositories(id) ON DELETE CASCADE,
+    triggered_by VARCHAR(50) NOT NULL,
+    status VARCHAR(50) NOT NULL,
+    started_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    finished_at TIMESTAMPTZ,
+    commit_sha VARCHAR(64),
+    files_listed INTEGER NOT NULL DEFAULT 0,
+    files_fetched INTEGER NOT NULL DEFAULT 0,
+    files_reused INTEGER NOT NULL DEFAULT 0,
+    files_skipped INTEGER NOT NULL DEFAULT 0,
+    bytes_fetched BIGINT NOT NULL DEFAULT 0,
+    api_calls INTEGER NOT NULL DEFAULT 0,
+    error TEXT
+);
+
+CREATE INDEX IF NOT EXISTS idx_sync_runs_repository_started ON sync_runs(repository_id, started_at DESC);
+
+COMMENT ON COLUMN sync_runs.triggered_by IS 'What started the run (scheduled, manual, initial, webhook)';
+COMMENT ON COLUMN sync_runs.status IS 'Outcome of the run (running, success, partial, failed)';
+COMMENT ON COLUMN sync_runs.api_calls IS 'Number of GitHub API requests made during the run';
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS snapshot_files;
+DROP TABLE IF EXISTS snapshots;
\ No newline at end of file
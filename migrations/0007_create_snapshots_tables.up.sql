@@ -0,0 +1,25 @@
+-- Immutable snapshots of the aggregated content, created when a sync changes it
+CREATE TABLE IF NOT EXISTS snapshots (
+    id SERIAL PRIMARY KEY,
+    repository_id INTEGER NOT NULL REFERENCES repositories(id) ON DELETE CASCADE,
+    content_hash CHAR(64) NOT NULL,
+    commit_sha VARCHAR(64),
+    file_count INTEGER NOT NULL DEFAULT 0,
+    size INTEGER NOT NULL DEFAULT 0,
+    content TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_snapshots_repository_created ON snapshots(repository_id, created_at DESC);
+
+-- Files contained in each snapshot, used for diffs between snapshots
+CREATE TABLE IF NOT EXISTS snapshot_files (
+    snapshot_id INTEGER NOT NULL REFERENCES snapshots(id) ON DELETE CASCADE,
+    path TEXT NOT NULL,
+    sha VARCHAR(64) NOT NULL,
+    content TEXT NOT NULL,
+    PRIMARY KEY (snapshot_id, path)
+);
+
+COMMENT ON COLUMN snapshots.content_hash IS 'SHA-256 of the aggregated content, used to skip unchanged snapshots';
+COMMENT ON COLUMN snapshots.commit_sha IS 'Commit the content was synced from, if known';
@@ -0,0 +1,3 @@
+ALTER TABLE repositories
+DROP COLUMN last_commit_sha,
+DROP COLUMN docs_tree_sha;
\ No newline at end of file
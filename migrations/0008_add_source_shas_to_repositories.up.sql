@@ -0,0 +1,6 @@
+ALTER TABLE repositories
+ADD COLUMN last_commit_sha VARCHAR(64),
+ADD COLUMN docs_tree_sha VARCHAR(64);
+
+COMMENT ON COLUMN repositories.last_commit_sha IS 'Branch head commit SHA at the last successful sync';
+COMMENT ON COLUMN repositories.docs_tree_sha IS 'Tree SHA of docs_path at the last successful sync';
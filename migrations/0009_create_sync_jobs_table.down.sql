@@ -0,0 +1 @@
+DROP TABLE IF EXISTS sync_jobs;
\ No newline at end of file
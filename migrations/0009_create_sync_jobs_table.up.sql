@@ -0,0 +1,22 @@
+-- Durable queue of sync jobs, claimed by workers with FOR UPDATE SKIP LOCKED
+CREATE TABLE IF NOT EXISTS sync_jobs (
+    id SERIAL PRIMARY KEY,
+    repository_id INTEGER NOT NULL REFERENCES repositories(id) ON DELETE CASCADE,
+    triggered_by VARCHAR(50) NOT NULL,
+    status VARCHAR(50) NOT NULL DEFAULT 'queued',
+    attempts INTEGER NOT NULL DEFAULT 0,
+    max_attempts INTEGER NOT NULL DEFAULT 3,
+    run_after TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_error TEXT,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    started_at TIMESTAMPTZ,
+    finished_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_sync_jobs_queued ON sync_jobs(run_after, id) WHERE status = 'queued';
+
+-- At most one queued job per repository
+CREATE UNIQUE INDEX IF NOT EXISTS idx_sync_jobs_one_queued_per_repository ON sync_jobs(repository_id) WHERE status = 'queued';
+
+COMMENT ON COLUMN sync_jobs.status IS 'Job state (queued, running, succeeded, failed, cancelled)';
+COMMENT ON COLUMN sync_jobs.run_after IS 'Earliest time the job may be claimed, used for retry backoff';
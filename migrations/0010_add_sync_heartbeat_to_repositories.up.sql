@@ -0,0 +1,4 @@
+ALTER TABLE repositories
+ADD COLUMN sync_heartbeat_at TIMESTAMPTZ;
+
+COMMENT ON COLUMN repositories.sync_heartbeat_at IS 'Last heartbeat of the in-progress sync; stale while syncing means the sync died';
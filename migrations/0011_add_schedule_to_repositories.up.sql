@@ -0,0 +1,4 @@
+ALTER TABLE repositories
+ADD COLUMN schedule VARCHAR(100);
+
+COMMENT ON COLUMN repositories.schedule IS 'Sync schedule: cron expression, "@every <duration>" or "manual"; NULL uses SYNC_INTERVAL';
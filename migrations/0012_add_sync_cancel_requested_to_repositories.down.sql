@@ -0,0 +1,2 @@
+ALTER TABLE repositories
+DROP COLUMN sync_cancel_requested;
@@ -0,0 +1,4 @@
+ALTER TABLE repositories
+ADD COLUMN sync_cancel_requested BOOLEAN NOT NULL DEFAULT FALSE;
+
+COMMENT ON COLUMN repositories.sync_cancel_requested IS 'Asks the instance running the sync to cancel it';
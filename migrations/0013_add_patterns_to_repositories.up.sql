@@ -0,0 +1,13 @@
+ALTER TABLE repositories
+ADD COLUMN patterns JSONB NOT NULL DEFAULT '[]'::jsonb;
+
+-- Migrate the comma-separated extension list into equivalent include patterns,
+-- normalizing each extension like pathfilter.ExtensionPatterns (" .MDX" becomes "**/*.mdx")
+UPDATE repositories
+SET patterns = COALESCE((
+    SELECT jsonb_agg('**/*.' || ltrim(btrim(lower(ext)), '.') ORDER BY ord)
+    FROM unnest(string_to_array(extensions, ',')) WITH ORDINALITY AS e(ext, ord)
+    WHERE ltrim(btrim(ext), '.') <> ''
+), '[]'::jsonb);
+
+COMMENT ON COLUMN repositories.patterns IS 'Ordered glob patterns selecting the files to sync; "!" excludes, the last match wins';
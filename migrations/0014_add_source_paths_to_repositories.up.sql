@@ -0,0 +1,8 @@
+ALTER TABLE repositories
+ADD COLUMN source_paths JSONB NOT NULL DEFAULT '[]'::jsonb;
+
+-- Migrate the single docs_path into the first source path
+UPDATE repositories
+SET source_paths = jsonb_build_array(jsonb_build_object('path', docs_path));
+
+COMMENT ON COLUMN repositories.source_paths IS 'Ordered source paths ({"path", "patterns"}) aggregated into one output';
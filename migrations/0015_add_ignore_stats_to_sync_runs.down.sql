@@ -0,0 +1,3 @@
+ALTER TABLE sync_runs
+DROP COLUMN ignore_rules,
+DROP COLUMN files_ignored;
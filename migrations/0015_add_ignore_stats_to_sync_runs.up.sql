@@ -0,0 +1,6 @@
+ALTER TABLE sync_runs
+ADD COLUMN ignore_rules JSONB NOT NULL DEFAULT '[]'::jsonb,
+ADD COLUMN files_ignored INTEGER NOT NULL DEFAULT 0;
+
+COMMENT ON COLUMN sync_runs.ignore_rules IS 'Effective .syncdocsignore rules of the run, prefixed with the file they came from';
+COMMENT ON COLUMN sync_runs.files_ignored IS 'Number of files excluded by .syncdocsignore rules';
@@ -0,0 +1,7 @@
+ALTER TABLE sync_runs
+DROP COLUMN ref;
+
+ALTER TABLE repositories
+DROP COLUMN ref_mode,
+DROP COLUMN ref_pattern,
+DROP COLUMN resolved_ref;
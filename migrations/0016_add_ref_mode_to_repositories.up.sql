@@ -0,0 +1,13 @@
+ALTER TABLE repositories
+ADD COLUMN ref_mode VARCHAR(20) NOT NULL DEFAULT 'branch',
+ADD COLUMN ref_pattern VARCHAR(255),
+ADD COLUMN resolved_ref VARCHAR(255);
+
+ALTER TABLE sync_runs
+ADD COLUMN ref VARCHAR(255);
+
+COMMENT ON COLUMN repositories.ref_mode IS 'How the synced ref is chosen: branch, tag, commit (pinned), latest_release or semver (resolved at each sync)';
+COMMENT ON COLUMN repositories.branch IS 'Branch, tag or commit SHA to sync from, depending on ref_mode; empty for latest_release and semver';
+COMMENT ON COLUMN repositories.ref_pattern IS 'Tag glob for the semver ref mode (e.g., v1.*); empty matches every tag';
+COMMENT ON COLUMN repositories.resolved_ref IS 'Ref synced by the last successful sync';
+COMMENT ON COLUMN sync_runs.ref IS 'Branch, tag or commit SHA the ref mode resolved to';
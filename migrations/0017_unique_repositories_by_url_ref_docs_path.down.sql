@@ -0,0 +1,5 @@
+DROP INDEX idx_repositories_url_ref_docs_path;
+
+-- Fails if the same URL was registered more than once in the meantime
+ALTER TABLE repositories
+ADD CONSTRAINT repositories_url_key UNIQUE (url);
@@ -0,0 +1,7 @@
+-- Allow the same GitHub repository to be registered once per ref and docs path,
+-- e.g. to track the docs of v1.x and v2.x side by side
+ALTER TABLE repositories
+DROP CONSTRAINT repositories_url_key;
+
+CREATE UNIQUE INDEX idx_repositories_url_ref_docs_path
+    ON repositories (url, ref_mode, COALESCE(branch, ''), COALESCE(ref_pattern, ''), docs_path);
@@ -0,0 +1,2 @@
+ALTER TABLE repository_files
+DROP COLUMN position;
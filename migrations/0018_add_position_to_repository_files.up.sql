@@ -0,0 +1,4 @@
+ALTER TABLE repository_files
+ADD COLUMN position INTEGER NOT NULL DEFAULT 0;
+
+COMMENT ON COLUMN repository_files.position IS 'Order of the file in the aggregated content; rows synced before this column existed sort by path';